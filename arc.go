@@ -0,0 +1,143 @@
+package lrucache
+
+import "container/list"
+
+// ARC returns an Adaptive Replacement Cache policy, which adapts between
+// recency and frequency according to the workload.
+//
+// The keys used only once recently are kept in T1, and the keys used at
+// least twice are kept in T2. The converted keys of the eliminated ones are
+// remembered in the ghost lists B1 and B2, a new key found in B1 enlarges
+// the target size of T1, and a new key found in B2 shrinks it, the target is
+// adapted before choosing the victim for the new key. The ghost
+// lists hold at most as many keys as the cache can hold, which is the max
+// size of cache, or the number of keys which fit if the cache is bounded by
+// cost or bytes.
+func ARC() Policy {
+	return &arcPolicy{ghosts: make(map[string]*list.Element)}
+}
+
+type arcPolicy struct {
+	t1, t2 entryList
+	b1, b2 list.List
+	// The elements of B1 and B2, their values are arcGhost.
+	ghosts map[string]*list.Element
+	// The target size of T1.
+	target int
+	// The number of keys the cache can hold, 0 if it is unknown yet.
+	capacity int
+	// The last entry returned by Victim, it becomes a ghost when removed.
+	victim *Entry
+	// The ghost hit by the key being inserted, it enters T2 by OnInsert.
+	hit   string
+	hitB2 bool
+}
+
+type arcGhost struct {
+	key  string
+	inB2 bool
+}
+
+// Adapt the target if the key being inserted is a ghost, it is called before
+// choosing the victim for the key, see insertPolicy.
+func (p *arcPolicy) beforeInsert(k string) {
+	g := p.ghosts[k]
+	if g == nil {
+		return
+	}
+	ghost := g.Value.(arcGhost)
+	if ghost.inB2 {
+		p.target -= atLeastOne(p.b1.Len() / p.b2.Len())
+		if p.target < 0 {
+			p.target = 0
+		}
+		p.b2.Remove(g)
+	} else {
+		p.target += atLeastOne(p.b2.Len() / p.b1.Len())
+		if p.capacity > 0 && p.target > p.capacity {
+			p.target = p.capacity
+		}
+		p.b1.Remove(g)
+	}
+	delete(p.ghosts, k)
+	p.hit, p.hitB2 = ghost.key, ghost.inB2
+}
+
+func (p *arcPolicy) OnInsert(e *Entry) {
+	hit := p.hit != "" && p.hit == e.key
+	p.hit, p.hitB2 = "", false
+	if hit {
+		// Hits a ghost, see the key as frequently used.
+		p.t2.pushBack(e)
+		return
+	}
+	p.t1.pushBack(e)
+	// The cache may be not full, e.g. some keys are deleted, so the ghosts
+	// are not trimmed by eliminating a key.
+	p.trimGhosts()
+}
+
+func (p *arcPolicy) OnAccess(e *Entry) {
+	e.list.remove(e)
+	p.t2.pushBack(e)
+}
+
+func (p *arcPolicy) OnRemove(e *Entry) {
+	inT2 := e.list == &p.t2
+	e.list.remove(e)
+	if e != p.victim {
+		// Deleted or expired, it is not a ghost.
+		return
+	}
+	p.victim = nil
+	if inT2 {
+		p.ghosts[e.key] = p.b2.PushBack(arcGhost{key: e.key, inB2: true})
+	} else {
+		p.ghosts[e.key] = p.b1.PushBack(arcGhost{key: e.key})
+	}
+	p.trimGhosts()
+}
+
+func (p *arcPolicy) setCapacity(n int) {
+	p.capacity = n
+	if n > 0 && p.target > n {
+		p.target = n
+	}
+	p.trimGhosts()
+}
+
+// Drop the oldest ghosts until T1 and B1 hold at most capacity keys, and
+// all lists hold at most twice of it.
+func (p *arcPolicy) trimGhosts() {
+	c := p.capacity
+	if c == 0 {
+		return
+	}
+	for p.b1.Len() > 0 && p.t1.len+p.b1.Len() > c {
+		p.dropGhost(&p.b1)
+	}
+	for p.b2.Len() > 0 && p.t1.len+p.t2.len+p.b1.Len()+p.b2.Len() > 2*c {
+		p.dropGhost(&p.b2)
+	}
+}
+
+func (p *arcPolicy) Victim() *Entry {
+	if p.t1.len > 0 && (p.t1.len > p.target || p.t1.len == p.target && p.hitB2 || p.t2.len == 0) {
+		p.victim = p.t1.head
+	} else {
+		p.victim = p.t2.head
+	}
+	return p.victim
+}
+
+// Drop the oldest ghost of l.
+func (p *arcPolicy) dropGhost(l *list.List) {
+	delete(p.ghosts, l.Remove(l.Front()).(arcGhost).key)
+}
+
+func atLeastOne(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
@@ -0,0 +1,204 @@
+package lrucache
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Check the resident lists hold all keys of cache and the ghosts are valid.
+func checkARC(t *testing.T, l *LRUCache) {
+	t.Helper()
+	p := l.policy.(*arcPolicy)
+	n := 0
+	for _, list := range []*entryList{&p.t1, &p.t2} {
+		for e := list.head; e != nil; e = e.lnext {
+			if e.list != list || l.m[e.key] != e {
+				t.Fatal("resident lists error")
+			}
+			n++
+		}
+	}
+	if n != len(l.m) || p.t1.len+p.t2.len != n {
+		t.Fatal("resident length error", n, len(l.m))
+	}
+	if len(p.ghosts) != p.b1.Len()+p.b2.Len() || (p.b1.Len()+p.b2.Len())/2 > l.maxSize {
+		t.Fatal("ghosts length error")
+	}
+	if c := p.capacity; c > 0 && (p.t1.len+p.b1.Len() > c || n+p.b1.Len()+p.b2.Len() > 2*c) {
+		t.Fatal("ghosts capacity error", c)
+	}
+	for k := range p.ghosts {
+		if l.m[k] != nil {
+			t.Fatal("resident ghost error")
+		}
+	}
+	if p.target < 0 || p.target > l.maxSize {
+		t.Fatal("target error", p.target)
+	}
+}
+
+func TestARC(t *testing.T) {
+	l := New(4, WithPolicy(ARC))
+	p := l.policy.(*arcPolicy)
+	for i := 0; i < 4; i++ {
+		l.Set(i, i)
+	}
+	l.Get(0)
+	l.Get(1)
+	if p.t1.len != 2 || p.t2.len != 2 {
+		t.Error("ARC lists error")
+	}
+	checkARC(t, l)
+
+	// The target is 0, so T1 is eliminated first and remembered in B1
+	l.Set(4, 4)
+	if l.Contains(2) || p.b1.Len() != 1 || !l.Contains(0) {
+		t.Error("ARC victim error")
+	}
+	checkARC(t, l)
+
+	// Hits the ghost in B1, the target grows and the key enters T2
+	l.Set(2, 2)
+	if p.target != 1 || p.b1.Len() != 1 || l.m[string(interfaceToBytes(2))].list != &p.t2 {
+		t.Error("ARC ghost hit error", p.target)
+	}
+	checkARC(t, l)
+
+	// A scan of cold keys doesn't flush the frequently used keys
+	for i := 10; i < 30; i++ {
+		l.Set(i, i)
+		checkARC(t, l)
+	}
+	if !l.Contains(1) || !l.Contains(2) || !l.Contains(29) {
+		t.Error("ARC scan error")
+	}
+
+	// Deleted keys are not ghosts
+	l.Delete(1)
+	if _, ok := p.ghosts[string(interfaceToBytes(1))]; ok {
+		t.Error("ARC delete error")
+	}
+	checkARC(t, l)
+	l.Purge()
+	if l.policy.(*arcPolicy).t1.len != 0 || len(l.policy.(*arcPolicy).ghosts) != 0 {
+		t.Error("ARC purge error")
+	}
+}
+
+func TestARC_AdaptBeforeVictim(t *testing.T) {
+	l := New(4, WithPolicy(ARC))
+	p := l.policy.(*arcPolicy)
+	for i := 0; i < 4; i++ {
+		l.Set(i, i)
+	}
+	l.Get(0)
+	l.Get(1)
+	l.Set(4, 4)
+	l.Get(3)
+	// T1 is [4], T2 is [0 1 3] and B1 is [2]. Hits the ghost in B1, the
+	// target grows to 1 before choosing the victim, so T2 is eliminated.
+	l.Set(2, 2)
+	if p.target != 1 || !l.Contains(4) || l.Contains(0) || l.m[string(interfaceToBytes(2))].list != &p.t2 {
+		t.Error("ARC adapt before victim error", p.target)
+	}
+	checkARC(t, l)
+
+	// Hits a ghost in B2 and the target shrinks to the size of T1, then T1
+	// is eliminated.
+	p = ARC().(*arcPolicy)
+	p.setCapacity(3)
+	a, b, c := &Entry{key: "a"}, &Entry{key: "b"}, &Entry{key: "c"}
+	p.OnInsert(a)
+	p.OnInsert(b)
+	p.OnInsert(c)
+	p.OnAccess(b)
+	p.OnAccess(c)
+	p.ghosts["x"] = p.b2.PushBack(arcGhost{key: "x", inB2: true})
+	p.ghosts["y"] = p.b1.PushBack(arcGhost{key: "y"})
+	p.target = 2
+	p.beforeInsert("x")
+	if p.target != 1 || p.Victim() != a {
+		t.Error("ARC ghost in B2 error", p.target)
+	}
+	p.OnRemove(a)
+	x := &Entry{key: "x"}
+	p.OnInsert(x)
+	if x.list != &p.t2 || p.hitB2 || p.b1.Len() != 2 {
+		t.Error("ARC insert ghost in B2 error")
+	}
+}
+
+func TestARC_Capacity(t *testing.T) {
+	l := New(4, WithPolicy(ARC))
+	p := l.policy.(*arcPolicy)
+	for i := 0; i < 4; i++ {
+		l.Set(i, i)
+		l.Get(i)
+	}
+	l.Set(4, 4) // 0 -> B2
+	l.Set(5, 5) // 4 -> B1
+	l.Get(5)
+	l.Set(6, 6) // 1 -> B2
+	l.Get(6)
+	l.Set(7, 7) // 2 -> B2
+	if p.b1.Len() != 1 || p.b2.Len() != 3 {
+		t.Fatal("ARC ghosts error")
+	}
+	checkARC(t, l)
+
+	// The deleted keys don't lower the capacity
+	l.MDelete(3)
+	l.MDelete(5)
+	l.MDelete(6)
+	l.MDelete(7)
+	l.Set(4, 4)
+	if p.target != 3 {
+		t.Error("ARC target after delete error", p.target)
+	}
+	checkARC(t, l)
+
+	// The capacity of a cache bounded by cost or bytes is the number of keys
+	// which fit
+	for _, l := range []*LRUCache{
+		NewWeighted(4, WithPolicy(ARC)),
+		New(64, WithPolicy(ARC), WithMaxBytes(4*(int64(len(interfaceToBytes(1)))+entryOverhead))),
+	} {
+		for i := 0; i < 100; i++ {
+			l.Set(i%10, i)
+			l.Get(i % 3)
+			checkARC(t, l)
+		}
+		if c := l.policy.(*arcPolicy).capacity; c != 4 || l.Len() != 4 {
+			t.Error("ARC capacity error", c)
+		}
+	}
+
+	l.Resize(2)
+	if p.capacity != 2 || p.target > 2 {
+		t.Error("ARC resize error")
+	}
+	checkARC(t, l)
+}
+
+func TestARC_Random(t *testing.T) {
+	l := New(64, WithPolicy(ARC))
+	for i := 0; i < 20000; i++ {
+		k := rand.Intn(256)
+		if i%5000 > 2500 {
+			// A frequency-heavy phase
+			k %= 48
+		}
+		switch rand.Intn(8) {
+		case 0:
+			l.Delete(k)
+		case 1, 2, 3:
+			l.Set(k, k)
+		default:
+			l.Get(k)
+		}
+		if i%100 == 0 {
+			checkARC(t, l)
+		}
+	}
+	checkARC(t, l)
+}
@@ -1,6 +1,8 @@
 package lrucache
 
 import (
+	"errors"
+	"github.com/ZYunH/sbconv"
 	"reflect"
 	"unsafe"
 )
@@ -11,7 +13,20 @@ type mockEFace struct {
 }
 
 func interfaceToBytes(args ...interface{}) []byte {
-	b := make([]byte, 0, len(args)*5)
+	return interfaceToBytesWithBuf(make([]byte, 0, len(args)*5), args...)
+}
+
+func interfaceToBytesWithBuf(b []byte, args ...interface{}) []byte {
+	b, err := appendKey(b, args...)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Append the converted args to b, ErrUnsupportedKey is returned if the type
+// of any argument is not supported.
+func appendKey(b []byte, args ...interface{}) ([]byte, error) {
 	var data unsafe.Pointer
 	for _, v := range args {
 		data = (*(*mockEFace)(unsafe.Pointer(&v))).data
@@ -123,126 +138,173 @@ func interfaceToBytes(args ...interface{}) []byte {
 
 			b = append(b, value...)
 		default:
-			panic("unknown type")
+			var ok bool
+			if b, ok = appendCustomKey(b, v); !ok {
+				return b, ErrUnsupportedKey
+			}
 		}
 	}
-	return b
+	return b, nil
 }
 
-func interfaceToBytesWithBuf(b []byte, args ...interface{}) []byte {
-	var data unsafe.Pointer
-	for _, v := range args {
-		data = (*(*mockEFace)(unsafe.Pointer(&v))).data
-		switch v.(type) {
-		case bool:
-			b = append(b, uint8(reflect.Bool), *(*byte)(data))
-		case uint8:
-			b = append(b, uint8(reflect.Uint8), *(*byte)(data))
-		case int8:
-			b = append(b, uint8(reflect.Int8), *(*byte)(data))
-		case uint16:
-			b = append(b, uint8(reflect.Uint16), (*(*[2]byte)(data))[0], (*(*[2]byte)(data))[1])
-		case int16:
-			b = append(b, uint8(reflect.Int16), (*(*[2]byte)(data))[0], (*(*[2]byte)(data))[1])
-		case uint32:
-			b = append(b, uint8(reflect.Uint32), (*(*[4]byte)(data))[0], (*(*[4]byte)(data))[1],
-				(*(*[4]byte)(data))[2], (*(*[4]byte)(data))[3])
-		case int32:
-			b = append(b, uint8(reflect.Int32), (*(*[4]byte)(data))[0], (*(*[4]byte)(data))[1],
-				(*(*[4]byte)(data))[2], (*(*[4]byte)(data))[3])
-		case float32:
-			b = append(b, uint8(reflect.Float32), (*(*[4]byte)(data))[0], (*(*[4]byte)(data))[1],
-				(*(*[4]byte)(data))[2], (*(*[4]byte)(data))[3])
-		case uint64:
-			b = append(b, uint8(reflect.Uint64), (*(*[8]byte)(data))[0], (*(*[8]byte)(data))[1],
-				(*(*[8]byte)(data))[2], (*(*[8]byte)(data))[3], (*(*[8]byte)(data))[4],
-				(*(*[8]byte)(data))[5], (*(*[8]byte)(data))[6], (*(*[8]byte)(data))[7])
-		case int64:
-			b = append(b, uint8(reflect.Int64), (*(*[8]byte)(data))[0], (*(*[8]byte)(data))[1],
-				(*(*[8]byte)(data))[2], (*(*[8]byte)(data))[3], (*(*[8]byte)(data))[4],
-				(*(*[8]byte)(data))[5], (*(*[8]byte)(data))[6], (*(*[8]byte)(data))[7])
-		case float64:
-			b = append(b, uint8(reflect.Float64), (*(*[8]byte)(data))[0], (*(*[8]byte)(data))[1],
-				(*(*[8]byte)(data))[2], (*(*[8]byte)(data))[3], (*(*[8]byte)(data))[4],
-				(*(*[8]byte)(data))[5], (*(*[8]byte)(data))[6], (*(*[8]byte)(data))[7])
-		case complex64:
-			b = append(b, uint8(reflect.Complex64), (*(*[8]byte)(data))[0], (*(*[8]byte)(data))[1],
-				(*(*[8]byte)(data))[2], (*(*[8]byte)(data))[3], (*(*[8]byte)(data))[4],
-				(*(*[8]byte)(data))[5], (*(*[8]byte)(data))[6], (*(*[8]byte)(data))[7])
-		case complex128:
-			b = append(b, uint8(reflect.Complex128), (*(*[16]byte)(data))[0], (*(*[16]byte)(data))[1],
-				(*(*[16]byte)(data))[2], (*(*[16]byte)(data))[3], (*(*[16]byte)(data))[4],
-				(*(*[16]byte)(data))[5], (*(*[16]byte)(data))[6], (*(*[16]byte)(data))[7],
-				(*(*[16]byte)(data))[8], (*(*[16]byte)(data))[9], (*(*[16]byte)(data))[10],
-				(*(*[16]byte)(data))[11], (*(*[16]byte)(data))[12], (*(*[16]byte)(data))[13],
-				(*(*[16]byte)(data))[14], (*(*[16]byte)(data))[15])
-		case int:
-			if bit == 64 {
-				b = append(b, uint8(reflect.Int), (*(*[8]byte)(data))[0], (*(*[8]byte)(data))[1],
-					(*(*[8]byte)(data))[2], (*(*[8]byte)(data))[3], (*(*[8]byte)(data))[4],
-					(*(*[8]byte)(data))[5], (*(*[8]byte)(data))[6], (*(*[8]byte)(data))[7])
-			} else if bit == 32 {
-				b = append(b, uint8(reflect.Int), (*(*[4]byte)(data))[0], (*(*[4]byte)(data))[1],
-					(*(*[4]byte)(data))[2], (*(*[4]byte)(data))[3])
+// Decode bytes converted by interfaceToBytes to the original arguments.
+//
+// The returned strings and byte slices are copied from b, so b can be
+// reused after the call. An error is returned if b is not a valid result of
+// interfaceToBytes on the same platform.
+func bytesToInterfaces(b []byte) ([]interface{}, error) {
+	args, _, err := decodeArgs(b, -1)
+	return args, err
+}
+
+// Decode at most n arguments from b, n < 0 means all. The returned int is
+// the number of used bytes.
+func decodeArgs(b []byte, n int) ([]interface{}, int, error) {
+	var args []interface{}
+	i := 0
+	for i < len(b) && (n < 0 || len(args) < n) {
+		kind := reflect.Kind(b[i])
+		i++
+		if kind == customKeyTag || kind == reflect.Struct {
+			var key interface{}
+			var size int
+			var err error
+			if kind == customKeyTag {
+				key, size, err = decodeCustomKey(b[i:])
 			} else {
-				panic("bit != (32 or 64)")
+				key, size, err = decodeStructKey(b[i:])
 			}
-		case uint:
-			if bit == 64 {
-				b = append(b, uint8(reflect.Uint), (*(*[8]byte)(data))[0], (*(*[8]byte)(data))[1],
-					(*(*[8]byte)(data))[2], (*(*[8]byte)(data))[3], (*(*[8]byte)(data))[4],
-					(*(*[8]byte)(data))[5], (*(*[8]byte)(data))[6], (*(*[8]byte)(data))[7])
-			} else if bit == 32 {
-				b = append(b, uint8(reflect.Uint), (*(*[4]byte)(data))[0], (*(*[4]byte)(data))[1],
-					(*(*[4]byte)(data))[2], (*(*[4]byte)(data))[3])
-			} else {
-				panic("bit != (32 or 64)")
+			if err != nil {
+				return nil, 0, err
 			}
-		case string:
-			// In this case, we insert a int to indicates how many
-			// bytes occupied by this string or []byte to avoid potential
-			// data conflict.
-			value := *(*string)(data)
-			bLen := len(value)
-			if bit == 64 {
-				b = append(b, uint8(reflect.String), (*(*[8]byte)(unsafe.Pointer(&bLen)))[0],
-					(*(*[8]byte)(unsafe.Pointer(&bLen)))[1], (*(*[8]byte)(unsafe.Pointer(&bLen)))[2],
-					(*(*[8]byte)(unsafe.Pointer(&bLen)))[3], (*(*[8]byte)(unsafe.Pointer(&bLen)))[4],
-					(*(*[8]byte)(unsafe.Pointer(&bLen)))[5], (*(*[8]byte)(unsafe.Pointer(&bLen)))[6],
-					(*(*[8]byte)(unsafe.Pointer(&bLen)))[7])
-			} else if bit == 32 {
-				b = append(b, uint8(reflect.String), (*(*[4]byte)(unsafe.Pointer(&bLen)))[0],
-					(*(*[4]byte)(unsafe.Pointer(&bLen)))[1], (*(*[4]byte)(unsafe.Pointer(&bLen)))[2],
-					(*(*[4]byte)(unsafe.Pointer(&bLen)))[3])
-			} else {
-				panic("bit != (32 or 64)")
+			args = append(args, key)
+			i += size
+			continue
+		}
+		size := kindSize(kind)
+		if size < 0 {
+			return nil, 0, errUnknownType
+		}
+		if len(b)-i < size {
+			return nil, 0, errShortBytes
+		}
+		switch kind {
+		case reflect.Bool:
+			args = append(args, b[i] != 0)
+		case reflect.Uint8:
+			args = append(args, b[i])
+		case reflect.Int8:
+			args = append(args, int8(b[i]))
+		case reflect.Uint16:
+			var v uint16
+			copy((*[2]byte)(unsafe.Pointer(&v))[:], b[i:])
+			args = append(args, v)
+		case reflect.Int16:
+			var v int16
+			copy((*[2]byte)(unsafe.Pointer(&v))[:], b[i:])
+			args = append(args, v)
+		case reflect.Uint32:
+			var v uint32
+			copy((*[4]byte)(unsafe.Pointer(&v))[:], b[i:])
+			args = append(args, v)
+		case reflect.Int32:
+			var v int32
+			copy((*[4]byte)(unsafe.Pointer(&v))[:], b[i:])
+			args = append(args, v)
+		case reflect.Float32:
+			var v float32
+			copy((*[4]byte)(unsafe.Pointer(&v))[:], b[i:])
+			args = append(args, v)
+		case reflect.Uint64:
+			var v uint64
+			copy((*[8]byte)(unsafe.Pointer(&v))[:], b[i:])
+			args = append(args, v)
+		case reflect.Int64:
+			var v int64
+			copy((*[8]byte)(unsafe.Pointer(&v))[:], b[i:])
+			args = append(args, v)
+		case reflect.Float64:
+			var v float64
+			copy((*[8]byte)(unsafe.Pointer(&v))[:], b[i:])
+			args = append(args, v)
+		case reflect.Complex64:
+			var v complex64
+			copy((*[8]byte)(unsafe.Pointer(&v))[:], b[i:])
+			args = append(args, v)
+		case reflect.Complex128:
+			var v complex128
+			copy((*[16]byte)(unsafe.Pointer(&v))[:], b[i:])
+			args = append(args, v)
+		case reflect.Int:
+			var v int
+			copy((*[bit / 8]byte)(unsafe.Pointer(&v))[:], b[i:])
+			args = append(args, v)
+		case reflect.Uint:
+			var v uint
+			copy((*[bit / 8]byte)(unsafe.Pointer(&v))[:], b[i:])
+			args = append(args, v)
+		case reflect.String, reflect.Slice:
+			// The size is the length of following string or []byte.
+			var bLen int
+			copy((*[bit / 8]byte)(unsafe.Pointer(&bLen))[:], b[i:])
+			i += size
+			if bLen < 0 || len(b)-i < bLen {
+				return nil, 0, errShortBytes
 			}
-
-			b = append(b, value...)
-		case []byte:
-			// In this case, we insert a int to indicates how many
-			// bytes occupied by this string or []byte to avoid potential
-			// data conflict.
-			value := *(*string)(data)
-			bLen := len(value)
-			if bit == 64 {
-				b = append(b, uint8(reflect.Slice), (*(*[8]byte)(unsafe.Pointer(&bLen)))[0],
-					(*(*[8]byte)(unsafe.Pointer(&bLen)))[1], (*(*[8]byte)(unsafe.Pointer(&bLen)))[2],
-					(*(*[8]byte)(unsafe.Pointer(&bLen)))[3], (*(*[8]byte)(unsafe.Pointer(&bLen)))[4],
-					(*(*[8]byte)(unsafe.Pointer(&bLen)))[5], (*(*[8]byte)(unsafe.Pointer(&bLen)))[6],
-					(*(*[8]byte)(unsafe.Pointer(&bLen)))[7])
-			} else if bit == 32 {
-				b = append(b, uint8(reflect.Slice), (*(*[4]byte)(unsafe.Pointer(&bLen)))[0],
-					(*(*[4]byte)(unsafe.Pointer(&bLen)))[1], (*(*[4]byte)(unsafe.Pointer(&bLen)))[2],
-					(*(*[4]byte)(unsafe.Pointer(&bLen)))[3])
+			if kind == reflect.String {
+				args = append(args, string(b[i:i+bLen]))
 			} else {
-				panic("bit != (32 or 64)")
+				args = append(args, append([]byte{}, b[i:i+bLen]...))
 			}
-
-			b = append(b, value...)
-		default:
-			panic("unknown type")
+			size = bLen
 		}
+		i += size
 	}
-	return b
+	if len(args) < n {
+		return nil, 0, errShortBytes
+	}
+	return args, i, nil
+}
+
+var (
+	errUnknownType = errors.New("unknown type")
+	errShortBytes  = errors.New("bytes are too short")
+)
+
+// Returns the number of bytes following the type of kind converted by
+// interfaceToBytes, it is the size of length for string and []byte, and
+// -1 means the kind is not supported.
+func kindSize(kind reflect.Kind) int {
+	switch kind {
+	case reflect.Bool, reflect.Uint8, reflect.Int8:
+		return 1
+	case reflect.Uint16, reflect.Int16:
+		return 2
+	case reflect.Uint32, reflect.Int32, reflect.Float32:
+		return 4
+	case reflect.Uint64, reflect.Int64, reflect.Float64, reflect.Complex64:
+		return 8
+	case reflect.Complex128:
+		return 16
+	case reflect.Int, reflect.Uint, reflect.String, reflect.Slice:
+		return bit / 8
+	}
+	return -1
+}
+
+// Decode a key in cache to the original arguments, see bytesToInterfaces.
+//
+// The key must be converted in the encoding, otherwise panic will occur.
+func decodeKey(k string, encoding KeyEncoding) []interface{} {
+	var args []interface{}
+	var err error
+	if encoding == CanonicalEncoding {
+		args, err = canonicalBytesToInterfaces(sbconv.StringToBytes(k))
+	} else {
+		args, err = bytesToInterfaces(sbconv.StringToBytes(k))
+	}
+	if err != nil {
+		panic(err)
+	}
+	return args
 }
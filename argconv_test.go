@@ -132,3 +132,78 @@ func TestInterfaceToBytesWithBuf(t *testing.T) {
 		t.Error("empty Bytes or String error")
 	}
 }
+
+func TestDecodeKey(t *testing.T) {
+	args := []interface{}{true, false, uint8(1), int8(-1), uint16(2), int16(-2), uint32(3), int32(-3),
+		float32(3.5), uint64(4), int64(-4), float64(4.5), complex64(5 + 1i), complex128(6 + 2i),
+		uint(7), int(-7), "222222", ""}
+	res := decodeKey(string(interfaceToBytes(args...)), NativeEncoding)
+	if len(res) != len(args) {
+		t.Fatal("decode key error")
+	}
+	for i := range args {
+		if res[i] != args[i] {
+			t.Error("decode key error", res[i], args[i])
+		}
+	}
+
+	res = decodeKey(string(interfaceToBytes([]byte("111111"), []byte(nil))), NativeEncoding)
+	if len(res) != 2 || string(res[0].([]byte)) != "111111" || len(res[1].([]byte)) != 0 {
+		t.Error("decode []byte error")
+	}
+}
+
+func TestBytesToInterfaces(t *testing.T) {
+	args := []interface{}{true, uint8(1), int64(-4), complex128(6 + 2i), int(-7), "222222"}
+	b := interfaceToBytes(append(args, []byte("111111"))...)
+	res, err := bytesToInterfaces(b)
+	if err != nil || len(res) != len(args)+1 {
+		t.Fatal("decode error")
+	}
+	for i := range args {
+		if res[i] != args[i] {
+			t.Error("decode error", res[i], args[i])
+		}
+	}
+
+	// The results are copied
+	for i := range b {
+		b[i] = 0
+	}
+	if res[5] != "222222" || string(res[6].([]byte)) != "111111" {
+		t.Error("decode copy error")
+	}
+
+	if res, err := bytesToInterfaces(nil); len(res) != 0 || err != nil {
+		t.Error("decode empty bytes error")
+	}
+
+	// Invalid bytes
+	b = interfaceToBytes(int64(1), "222222")
+	for i := 1; i < len(b); i++ {
+		if i == 9 {
+			// The end of int64
+			continue
+		}
+		if _, err := bytesToInterfaces(b[:i]); err != errShortBytes {
+			t.Error("decode short bytes error", i)
+		}
+	}
+	if _, err := bytesToInterfaces([]byte{0}); err != errUnknownType {
+		t.Error("decode unknown type error")
+	}
+	b = interfaceToBytes("222222")
+	b[1] = 255
+	if _, err := bytesToInterfaces(b); err != errShortBytes {
+		t.Error("decode invalid length error")
+	}
+
+	func() {
+		defer func() {
+			if recover() != errUnknownType {
+				t.Error("decode key panic error")
+			}
+		}()
+		decodeKey("\x00", NativeEncoding)
+	}()
+}
@@ -0,0 +1,233 @@
+package lrucache
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+)
+
+// KeyEncoding is the way to convert keys to bytes.
+type KeyEncoding uint8
+
+const (
+	// NativeEncoding copies the memory of keys, it is the fastest one, but
+	// the results depend on the byte order and the size of int of platform.
+	NativeEncoding KeyEncoding = iota
+	// CanonicalEncoding converts keys to the same bytes on all platforms,
+	// integers are fixed-width little-endian (int and uint are 8 bytes), the
+	// lengths of strings and []byte are varints, and custom key types are
+	// identified by their names.
+	CanonicalEncoding
+)
+
+// Append the converted args to b in CanonicalEncoding, ErrUnsupportedKey is
+// returned if the type of any argument is not supported.
+func appendCanonicalKey(b []byte, args ...interface{}) ([]byte, error) {
+	for _, v := range args {
+		switch v := v.(type) {
+		case bool:
+			if v {
+				b = append(b, uint8(reflect.Bool), 1)
+			} else {
+				b = append(b, uint8(reflect.Bool), 0)
+			}
+		case uint8:
+			b = append(b, uint8(reflect.Uint8), v)
+		case int8:
+			b = append(b, uint8(reflect.Int8), uint8(v))
+		case uint16:
+			b = appendUint16(append(b, uint8(reflect.Uint16)), v)
+		case int16:
+			b = appendUint16(append(b, uint8(reflect.Int16)), uint16(v))
+		case uint32:
+			b = appendUint32(append(b, uint8(reflect.Uint32)), v)
+		case int32:
+			b = appendUint32(append(b, uint8(reflect.Int32)), uint32(v))
+		case float32:
+			b = appendUint32(append(b, uint8(reflect.Float32)), math.Float32bits(v))
+		case uint64:
+			b = appendUint64(append(b, uint8(reflect.Uint64)), v)
+		case int64:
+			b = appendUint64(append(b, uint8(reflect.Int64)), uint64(v))
+		case float64:
+			b = appendUint64(append(b, uint8(reflect.Float64)), math.Float64bits(v))
+		case complex64:
+			b = appendUint32(append(b, uint8(reflect.Complex64)), math.Float32bits(real(v)))
+			b = appendUint32(b, math.Float32bits(imag(v)))
+		case complex128:
+			b = appendUint64(append(b, uint8(reflect.Complex128)), math.Float64bits(real(v)))
+			b = appendUint64(b, math.Float64bits(imag(v)))
+		case int:
+			b = appendUint64(append(b, uint8(reflect.Int)), uint64(v))
+		case uint:
+			b = appendUint64(append(b, uint8(reflect.Uint)), uint64(v))
+		case string:
+			b = appendUvarint(append(b, uint8(reflect.String)), uint64(len(v)))
+			b = append(b, v...)
+		case []byte:
+			b = appendUvarint(append(b, uint8(reflect.Slice)), uint64(len(v)))
+			b = append(b, v...)
+		default:
+			t := lookupCustomKeyType(reflect.TypeOf(v))
+			if t == nil {
+				return b, ErrUnsupportedKey
+			}
+			if t.plan != nil {
+				var err error
+				if b, err = appendCanonicalStructKey(b, t, v); err != nil {
+					return b, err
+				}
+				continue
+			}
+			if v = t.encoderKey(v); v == nil {
+				return b, ErrUnsupportedKey
+			}
+			b = appendUvarint(append(b, customKeyTag), uint64(len(t.name)))
+			b = append(b, t.name...)
+			// Convert the key to the tail of b, then move it after its length.
+			start := len(b)
+			b = t.encode(b, v)
+			var lenBuf [binary.MaxVarintLen64]byte
+			n := binary.PutUvarint(lenBuf[:], uint64(len(b)-start))
+			b = append(b, lenBuf[:n]...)
+			copy(b[start+n:], b[start:len(b)-n])
+			copy(b[start:], lenBuf[:n])
+		}
+	}
+	return b, nil
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v), byte(v>>8))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24),
+		byte(v>>32), byte(v>>40), byte(v>>48), byte(v>>56))
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// Decode bytes converted by appendCanonicalKey to the original arguments,
+// see bytesToInterfaces.
+func canonicalBytesToInterfaces(b []byte) ([]interface{}, error) {
+	args, _, err := canonicalDecodeArgs(b, -1)
+	return args, err
+}
+
+// Decode at most n arguments from b in CanonicalEncoding, n < 0 means all.
+// The returned int is the number of used bytes.
+func canonicalDecodeArgs(b []byte, n int) ([]interface{}, int, error) {
+	var args []interface{}
+	i := 0
+	for i < len(b) && (n < 0 || len(args) < n) {
+		kind := reflect.Kind(b[i])
+		i++
+		size := 0
+		switch kind {
+		case reflect.Bool, reflect.Uint8, reflect.Int8:
+			size = 1
+		case reflect.Uint16, reflect.Int16:
+			size = 2
+		case reflect.Uint32, reflect.Int32, reflect.Float32:
+			size = 4
+		case reflect.Uint64, reflect.Int64, reflect.Float64, reflect.Complex64, reflect.Int, reflect.Uint:
+			size = 8
+		case reflect.Complex128:
+			size = 16
+		case reflect.String, reflect.Slice, customKeyTag, reflect.Struct:
+			// The size is the length of following data.
+			bLen, n := binary.Uvarint(b[i:])
+			if n <= 0 || uint64(len(b)-i-n) < bLen {
+				return nil, 0, errShortBytes
+			}
+			i += n
+			size = int(bLen)
+		default:
+			return nil, 0, errUnknownType
+		}
+		if len(b)-i < size {
+			return nil, 0, errShortBytes
+		}
+
+		data := b[i : i+size]
+		i += size
+		switch kind {
+		case reflect.Bool:
+			args = append(args, data[0] != 0)
+		case reflect.Uint8:
+			args = append(args, data[0])
+		case reflect.Int8:
+			args = append(args, int8(data[0]))
+		case reflect.Uint16:
+			args = append(args, binary.LittleEndian.Uint16(data))
+		case reflect.Int16:
+			args = append(args, int16(binary.LittleEndian.Uint16(data)))
+		case reflect.Uint32:
+			args = append(args, binary.LittleEndian.Uint32(data))
+		case reflect.Int32:
+			args = append(args, int32(binary.LittleEndian.Uint32(data)))
+		case reflect.Float32:
+			args = append(args, math.Float32frombits(binary.LittleEndian.Uint32(data)))
+		case reflect.Uint64:
+			args = append(args, binary.LittleEndian.Uint64(data))
+		case reflect.Int64:
+			args = append(args, int64(binary.LittleEndian.Uint64(data)))
+		case reflect.Float64:
+			args = append(args, math.Float64frombits(binary.LittleEndian.Uint64(data)))
+		case reflect.Complex64:
+			args = append(args, complex(math.Float32frombits(binary.LittleEndian.Uint32(data)),
+				math.Float32frombits(binary.LittleEndian.Uint32(data[4:]))))
+		case reflect.Complex128:
+			args = append(args, complex(math.Float64frombits(binary.LittleEndian.Uint64(data)),
+				math.Float64frombits(binary.LittleEndian.Uint64(data[8:]))))
+		case reflect.Int:
+			args = append(args, int(binary.LittleEndian.Uint64(data)))
+		case reflect.Uint:
+			args = append(args, uint(binary.LittleEndian.Uint64(data)))
+		case reflect.String:
+			args = append(args, string(data))
+		case reflect.Slice:
+			args = append(args, append([]byte{}, data...))
+		case reflect.Struct:
+			// The data is the name of type, followed by the fields.
+			t := loadCustomKeyRegistry().plans[string(data)]
+			if t == nil {
+				return nil, 0, errUnknownType
+			}
+			fields, n, err := canonicalDecodeArgs(b[i:], len(t.plan.fields))
+			if err != nil {
+				return nil, 0, err
+			}
+			args = append(args, t.structKey(fields, b[i:i+n]))
+			i += n
+		case customKeyTag:
+			// The data is the name of type, followed by the length of key and key.
+			t := lookupCustomKeyTypeByName(string(data))
+			if t == nil {
+				return nil, 0, errUnknownType
+			}
+			bLen, n := binary.Uvarint(b[i:])
+			if n <= 0 || uint64(len(b)-i-n) < bLen {
+				return nil, 0, errShortBytes
+			}
+			i += n
+			args = append(args, CustomKey{Type: t.typ, Data: append([]byte{}, b[i:i+int(bLen)]...)})
+			i += int(bLen)
+		}
+	}
+	if len(args) < n {
+		return nil, 0, errShortBytes
+	}
+	return args, i, nil
+}
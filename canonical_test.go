@@ -0,0 +1,126 @@
+package lrucache
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func BenchmarkAppendCanonicalKey(b *testing.B) {
+	buf := make([]byte, 0, 128)
+	for i := 0; i < b.N; i++ {
+		appendCanonicalKey(buf, i, i+1, "testString")
+	}
+}
+
+func TestAppendCanonicalKey(t *testing.T) {
+	b, err := appendCanonicalKey(nil, true, uint8(1), int8(-1), uint16(0x0102), int16(-2), uint32(0x01020304),
+		int32(-3), float32(1), uint64(0x0102030405060708), int64(-4), float64(1), complex64(1+1i),
+		complex128(1+1i), uint(7), int(-7), "ab", []byte("c"))
+	if err != nil {
+		t.Fatal("convert error")
+	}
+	expected := "\x01\x01" + "\x08\x01" + "\x03\xff" + "\x09\x02\x01" + "\x04\xfe\xff" +
+		"\x0a\x04\x03\x02\x01" + "\x05\xfd\xff\xff\xff" + "\x0d\x00\x00\x80\x3f" +
+		"\x0b\x08\x07\x06\x05\x04\x03\x02\x01" + "\x06\xfc\xff\xff\xff\xff\xff\xff\xff" +
+		"\x0e\x00\x00\x00\x00\x00\x00\xf0\x3f" + "\x0f\x00\x00\x80\x3f\x00\x00\x80\x3f" +
+		"\x10\x00\x00\x00\x00\x00\x00\xf0\x3f\x00\x00\x00\x00\x00\x00\xf0\x3f" +
+		"\x07\x07\x00\x00\x00\x00\x00\x00\x00" + "\x02\xf9\xff\xff\xff\xff\xff\xff\xff" +
+		"\x18\x02ab" + "\x17\x01c"
+	if string(b) != expected {
+		t.Errorf("canonical encoding error\n%q\n%q", b, expected)
+	}
+
+	// Long strings use multi-byte varint lengths
+	long := string(make([]byte, 300))
+	b, _ = appendCanonicalKey(nil, long)
+	if len(b) != 1+2+300 || b[1] != 0xac || b[2] != 0x02 {
+		t.Error("varint length error")
+	}
+
+	if _, err := appendCanonicalKey(nil, []int{}); err != ErrUnsupportedKey {
+		t.Error("unsupported key error")
+	}
+}
+
+func TestCanonicalBytesToInterfaces(t *testing.T) {
+	now := time.Now()
+	args := []interface{}{true, false, uint8(1), int8(-1), uint16(2), int16(-2), uint32(3), int32(-3),
+		float32(3.5), uint64(4), int64(-4), float64(4.5), complex64(5 + 1i), complex128(6 + 2i),
+		uint(7), int(-7), "222222", "", testUserID{"a", 1}, now}
+	b, _ := appendCanonicalKey(nil, append(args, []byte("111111"))...)
+	res, err := canonicalBytesToInterfaces(b)
+	if err != nil || len(res) != len(args)+1 {
+		t.Fatal("decode error", err)
+	}
+	for i := range args[:len(args)-2] {
+		if res[i] != args[i] {
+			t.Error("decode error", res[i], args[i])
+		}
+	}
+	if k := res[len(args)-2].(CustomKey); k.Type != reflect.TypeOf(testUserID{}) || string(k.Data) != "a\x01\x00" {
+		t.Error("decode custom key error")
+	}
+	if k := res[len(args)-1].(CustomKey); k.Type != reflect.TypeOf(now) || string(k.Data) != now.Format(time.RFC3339Nano) {
+		t.Error("decode registered key error")
+	}
+	if string(res[len(args)].([]byte)) != "111111" {
+		t.Error("decode []byte error")
+	}
+
+	// Invalid bytes
+	b, _ = appendCanonicalKey(nil, int64(1), "222222", testUserID{"a", 1})
+	for i := 1; i < len(b); i++ {
+		if i == 9 || i == 17 {
+			// The end of int64 and string
+			continue
+		}
+		if _, err := canonicalBytesToInterfaces(b[:i]); err != errShortBytes {
+			t.Error("decode short bytes error", i, err)
+		}
+	}
+	if _, err := canonicalBytesToInterfaces([]byte{0}); err != errUnknownType {
+		t.Error("decode unknown type error")
+	}
+	if _, err := canonicalBytesToInterfaces([]byte{customKeyTag, 1, 'x', 0}); err != errUnknownType {
+		t.Error("decode unknown custom key error")
+	}
+}
+
+func TestCanonicalEncoding(t *testing.T) {
+	var keys []string
+	l := New(2, WithKeyEncoding(CanonicalEncoding), OnEvict(func(key []interface{}, value interface{}, reason EvictReason) {
+		keys = append(keys, fmt.Sprint(key...))
+	}))
+	l.Set(1, 1)
+	l.MSet(1, "2", testUserID{"a", 1}, 2)
+	if v, ok := l.Get(1); v != 1 || !ok {
+		t.Error("get error")
+	}
+	if v, ok := l.MGet(1, "2", testUserID{"a", 1}); v != 2 || !ok {
+		t.Error("MGet error")
+	}
+	if _, ok := l.m["\x02\x01\x00\x00\x00\x00\x00\x00\x00"]; !ok {
+		t.Error("canonical key error")
+	}
+	if _, _, err := l.TryGet([]int{}); err != ErrUnsupportedKey {
+		t.Error("unsupported key error")
+	}
+	if fmt.Sprint(l.Keys()) != fmt.Sprint([][]interface{}{{1, "2", CustomKey{reflect.TypeOf(testUserID{}), []byte("a\x01\x00")}}, {1}}) {
+		t.Error("decode keys error", l.Keys())
+	}
+	l.Delete(1)
+	if len(keys) != 1 || keys[0] != "1" {
+		t.Error("evict callback error")
+	}
+
+	s := NewSharded(4, 64, WithKeyEncoding(CanonicalEncoding))
+	s.Set(1, 1)
+	if v, ok := s.Get(1); v != 1 || !ok {
+		t.Error("sharded get error")
+	}
+	if _, ok := s.shard("\x02\x01\x00\x00\x00\x00\x00\x00\x00").m["\x02\x01\x00\x00\x00\x00\x00\x00\x00"]; !ok {
+		t.Error("sharded canonical key error")
+	}
+}
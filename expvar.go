@@ -0,0 +1,64 @@
+package lrucache
+
+import (
+	"errors"
+	"expvar"
+	"sync"
+)
+
+// ErrDuplicateName is returned by Publish if the name is already used by
+// another expvar.Var.
+var ErrDuplicateName = errors.New("duplicate expvar name")
+
+// Protects the check and publication of expvar names.
+var publishLock sync.Mutex
+
+// Publish exposes the statistics of cache as an expvar.Var of name, which
+// renders live JSON in /debug/vars, e.g.
+//
+//	{"hits":1,"misses":1,"expired":0,"len":1,"cap":64,"hit_ratio":0.5}
+//
+// ErrDuplicateName is returned if the name is already used, since a Var
+// can't be unpublished.
+func (c *LRUCache) Publish(name string) error {
+	return publish(name, c)
+}
+
+// Publish exposes the statistics of all shards as an expvar.Var of name, see
+// LRUCache.Publish.
+func (c *ShardedCache) Publish(name string) error {
+	return publish(name, c)
+}
+
+func publish(name string, c interface {
+	Stats() Stats
+	Cap() int
+}) error {
+	publishLock.Lock()
+	defer publishLock.Unlock()
+	if expvar.Get(name) != nil {
+		return ErrDuplicateName
+	}
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		s := c.Stats()
+		return expvarStats{
+			Hits:     s.Hits,
+			Misses:   s.Misses,
+			Expired:  s.Expired,
+			Len:      s.Len,
+			Cap:      c.Cap(),
+			HitRatio: s.HitRatio(),
+		}
+	}))
+	return nil
+}
+
+// The JSON rendered by the published expvar.Var.
+type expvarStats struct {
+	Hits     int64   `json:"hits"`
+	Misses   int64   `json:"misses"`
+	Expired  int64   `json:"expired"`
+	Len      int     `json:"len"`
+	Cap      int     `json:"cap"`
+	HitRatio float64 `json:"hit_ratio"`
+}
@@ -0,0 +1,55 @@
+package lrucache
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"testing"
+)
+
+// Returns a name which is not published yet, since the names of expvar are
+// global and the test may run several times, e.g. with -count.
+func unpublishedName(prefix string) string {
+	for i := 0; ; i++ {
+		if name := fmt.Sprintf("%s_%d", prefix, i); expvar.Get(name) == nil {
+			return name
+		}
+	}
+}
+
+func TestLRUCache_Publish(t *testing.T) {
+	name := unpublishedName("lrucache_test")
+	l := New(64)
+	if l.Publish(name) != nil {
+		t.Fatal("publish error")
+	}
+	if l.Publish(name) != ErrDuplicateName || NewSharded(2, 8).Publish(name) != ErrDuplicateName {
+		t.Error("publish duplicate name error")
+	}
+
+	var s expvarStats
+	// No lookup yet, the hit ratio must be valid JSON
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &s); err != nil || s.HitRatio != 0 || s.Cap != 64 {
+		t.Error("render error", err)
+	}
+
+	l.Set(1, 1)
+	l.Get(1)
+	l.Get(2)
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &s); err != nil {
+		t.Fatal("render error", err)
+	}
+	if s != (expvarStats{Hits: 1, Misses: 1, Len: 1, Cap: 64, HitRatio: 0.5}) {
+		t.Error("render error", s)
+	}
+
+	name = unpublishedName("lrucache_sharded_test")
+	sc := NewSharded(2, 8)
+	if sc.Publish(name) != nil {
+		t.Fatal("publish error")
+	}
+	sc.Set(1, 1)
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &s); err != nil || s.Len != 1 || s.Cap != 8 {
+		t.Error("render sharded error", err)
+	}
+}
@@ -1,5 +1,5 @@
 module github.com/ZYunH/lrucache
 
-go 1.12
+go 1.18
 
 require github.com/ZYunH/sbconv v0.1.0
@@ -0,0 +1,93 @@
+package lrucache
+
+import "time"
+
+// Range calls fn for every key and value in cache from the most recently
+// used one to the least recently used one, until fn returns false. The
+// expired keys are skipped, and the LRU order is not updated.
+//
+// The fn is called while holding the lock of cache, so it must not use the
+// cache, otherwise a deadlock will occur.
+func (c *LRUCache) Range(fn func(key []interface{}, value interface{}) bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := time.Now().UnixNano()
+	n := c.root
+	for {
+		n = n.prev
+		if n.key != "" && (n.expire == 0 || n.expire > now) {
+			if !fn(decodeKey(n.key, c.encoding), n.value) {
+				break
+			}
+		}
+		if n == c.root {
+			break
+		}
+	}
+}
+
+// Keys returns the keys in cache from the most recently used one to the
+// least recently used one, see Range.
+func (c *LRUCache) Keys() [][]interface{} {
+	keys := make([][]interface{}, 0, c.Len())
+	c.Range(func(key []interface{}, value interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// Oldest returns the least recently used key and its value without updating
+// the LRU order, ok is false if the cache is empty.
+func (c *LRUCache) Oldest() (key []interface{}, value interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := time.Now().UnixNano()
+	n := c.root
+	for {
+		if n.key != "" && (n.expire == 0 || n.expire > now) {
+			key, value, ok = decodeKey(n.key, c.encoding), n.value, true
+			break
+		}
+		if n = n.next; n == c.root {
+			break
+		}
+	}
+	return
+}
+
+// Newest returns the most recently used key and its value without updating
+// the LRU order, ok is false if the cache is empty.
+func (c *LRUCache) Newest() (key []interface{}, value interface{}, ok bool) {
+	c.Range(func(k []interface{}, v interface{}) bool {
+		key, value, ok = k, v, true
+		return false
+	})
+	return
+}
+
+// Range calls fn for every key and value in all shards, the keys are ordered
+// from the most recently used one to the least recently used one in each
+// shard, see LRUCache.Range.
+func (c *ShardedCache) Range(fn func(key []interface{}, value interface{}) bool) {
+	stop := false
+	for _, s := range c.shards {
+		s.Range(func(key []interface{}, value interface{}) bool {
+			stop = !fn(key, value)
+			return !stop
+		})
+		if stop {
+			return
+		}
+	}
+}
+
+// Keys returns the keys in all shards, see ShardedCache.Range.
+func (c *ShardedCache) Keys() [][]interface{} {
+	keys := make([][]interface{}, 0, c.Len())
+	c.Range(func(key []interface{}, value interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
@@ -0,0 +1,89 @@
+package lrucache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLRUCache_Range(t *testing.T) {
+	l := New(4)
+	if _, _, ok := l.Oldest(); ok {
+		t.Error("Oldest of empty cache error")
+	}
+	if _, _, ok := l.Newest(); ok {
+		t.Error("Newest of empty cache error")
+	}
+	if len(l.Keys()) != 0 {
+		t.Error("Keys of empty cache error")
+	}
+
+	l.Set(1, 1)
+	l.MSet(2, "2", 2)
+	l.Set(3, 3)
+	l.Get(1) // Now is (2,"2"), 3, 1
+
+	if keys := fmt.Sprint(l.Keys()); keys != "[[1] [3] [2 2]]" {
+		t.Error("Keys error", keys)
+	}
+	key, value, ok := l.Oldest()
+	if fmt.Sprint(key) != "[2 2]" || value != 2 || !ok {
+		t.Error("Oldest error")
+	}
+	key, value, ok = l.Newest()
+	if fmt.Sprint(key) != "[1]" || value != 1 || !ok {
+		t.Error("Newest error")
+	}
+
+	// Stop ranging
+	var values []interface{}
+	l.Range(func(key []interface{}, value interface{}) bool {
+		values = append(values, value)
+		return len(values) < 2
+	})
+	if fmt.Sprint(values) != "[1 3]" {
+		t.Error("Range error", values)
+	}
+
+	// Full cache, expired keys are skipped
+	l.SetWithTTL(4, 4, time.Millisecond)
+	l.Set(5, 5) // Now is 3(root), 1, 4, 5
+	time.Sleep(time.Millisecond * 5)
+	if keys := fmt.Sprint(l.Keys()); keys != "[[5] [1] [3]]" {
+		t.Error("Keys error", keys)
+	}
+	if key, _, _ := l.Oldest(); fmt.Sprint(key) != "[3]" {
+		t.Error("Oldest error")
+	}
+	l.Delete(3)
+	l.Delete(5)
+	if key, _, _ := l.Oldest(); fmt.Sprint(key) != "[1]" {
+		t.Error("Oldest error")
+	}
+	if key, _, _ := l.Newest(); fmt.Sprint(key) != "[1]" {
+		t.Error("Newest error")
+	}
+
+	// The order is not updated
+	if l.root.next.value != 1 {
+		t.Error("Range updates order error")
+	}
+}
+
+func TestShardedCache_Range(t *testing.T) {
+	l := NewSharded(4, 64)
+	for i := 0; i < 32; i++ {
+		l.Set(i, i)
+	}
+	if len(l.Keys()) != 32 {
+		t.Error("Keys error")
+	}
+	count := 0
+	l.Range(func(key []interface{}, value interface{}) bool {
+		count++
+		return count < 10
+	})
+	if count != 10 {
+		t.Error("Range error")
+	}
+}
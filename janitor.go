@@ -0,0 +1,61 @@
+package lrucache
+
+import "time"
+
+// The max number of nodes checked by the janitor while holding the lock.
+const janitorBatchSize = 256
+
+func (c *LRUCache) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.sweep(janitorBatchSize)
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// Walk the linked list from root and remove the expired keys.
+//
+// The lock is released after every batchSize nodes, so other goroutines
+// can use the cache during a sweep. The cursor is kept valid by remove,
+// nodes moved by get may be checked twice or skipped, they will be checked
+// by the next sweep.
+func (c *LRUCache) sweep(batchSize int) {
+	c.lock.Lock()
+	steps := len(c.m) + 1
+	c.cursor = c.root
+	c.lock.Unlock()
+
+	for steps > 0 {
+		c.lock.Lock()
+		now := time.Now().UnixNano()
+		for i := 0; i < batchSize && steps > 0; i++ {
+			n := c.cursor
+			c.cursor = n.next
+			if n.expire != 0 && n.expire <= now {
+				c.remove(n, EvictExpired)
+			}
+			steps--
+		}
+		c.lock.Unlock()
+	}
+
+	c.lock.Lock()
+	c.cursor = nil
+	c.lock.Unlock()
+}
+
+// Close stops the janitor started by WithJanitor, it is safe to call Close
+// multiple times or without a janitor. The cache is still usable after Close.
+func (c *LRUCache) Close() {
+	if c.stop == nil {
+		return
+	}
+	c.closeOnce.Do(func() {
+		close(c.stop)
+	})
+}
@@ -0,0 +1,74 @@
+package lrucache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUCache_Sweep(t *testing.T) {
+	l := New(8)
+	for i := 0; i < 8; i++ {
+		if i%2 == 0 {
+			l.SetWithTTL(i, i, time.Millisecond)
+		} else {
+			l.Set(i, i)
+		}
+	}
+	time.Sleep(time.Millisecond * 5)
+
+	l.sweep(3)
+	if l.Len() != 4 || l.cursor != nil {
+		t.Error("sweep error")
+	}
+	for i := 0; i < 8; i++ {
+		if _, ok := l.Get(i); ok != (i%2 == 1) {
+			t.Error("sweep error")
+		}
+	}
+	if l.Expired() != 0 {
+		t.Error("sweep should not count expired lookups")
+	}
+
+	// The removed nodes must be reused
+	for i := 8; i < 12; i++ {
+		if l.Set(i, i) {
+			t.Error("eliminate key after sweep error")
+		}
+	}
+	if !l.Set(12, 12) {
+		t.Error("eliminate key error")
+	}
+}
+
+func TestLRUCache_Janitor(t *testing.T) {
+	l := New(64, WithJanitor(time.Millisecond))
+	defer l.Close()
+	for i := 0; i < 32; i++ {
+		l.SetWithTTL(i, i, time.Millisecond)
+	}
+	l.Set("foo", "bar")
+
+	time.Sleep(time.Millisecond * 50)
+	if l.Len() != 1 {
+		t.Error("janitor error")
+	}
+
+	l.Close()
+	l.Close()
+	New(1).Close()
+}
+
+func TestLRUCache_JanitorDataRaces(t *testing.T) {
+	l := New(64, WithJanitor(time.Microsecond*100))
+	defer l.Close()
+	for i := 0; i < 10; i++ {
+		go func() {
+			for j := 0; j < 1000; j++ {
+				l.SetWithTTL(j, j, time.Microsecond*time.Duration(j%10))
+				l.Get(j - 1)
+				l.Delete(j - 2)
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond * 100)
+}
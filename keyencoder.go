@@ -0,0 +1,297 @@
+package lrucache
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// KeyEncoder is implemented by custom key types, AppendCacheKey appends the
+// bytes which identify the key to b and returns the extended slice.
+//
+// Keys of different types never conflict with each other, since the type is
+// also converted. A pointer to key is converted as the key it points to, and
+// AppendCacheKey can also have a pointer receiver.
+type KeyEncoder interface {
+	AppendCacheKey(b []byte) []byte
+}
+
+// CustomKey is a decoded key of custom type, which is converted by
+// KeyEncoder or the encoder registered by RegisterKeyEncoder.
+type CustomKey struct {
+	Type reflect.Type
+	Data []byte
+}
+
+// The type of custom keys, it never conflicts with the types of built-in
+// keys, which are reflect.Kind.
+const customKeyTag = 0xff
+
+type customKeyType struct {
+	// The id is used by NativeEncoding, and the name is used by
+	// CanonicalEncoding, which is the same on all platforms.
+	id      uint32
+	name    string
+	typ     reflect.Type
+	encoder func(b []byte, key interface{}) []byte
+	// The plan of struct, array and named built-in keys, which have no
+	// encoder.
+	plan *keyPlan
+}
+
+type customKeyRegistry struct {
+	types map[reflect.Type]*customKeyType
+	ids   map[uint32]*customKeyType
+	names map[string]*customKeyType
+	// The struct and array types with plans by name, they are kept after
+	// an encoder of the same type is registered, then the keys converted by
+	// the plans can still be decoded.
+	plans map[string]*customKeyType
+}
+
+// The registry of custom key types, it is read without lock and copied on
+// write.
+var (
+	customKeyTypes     atomic.Value // *customKeyRegistry
+	customKeyTypesLock sync.Mutex
+)
+
+func loadCustomKeyRegistry() *customKeyRegistry {
+	r, _ := customKeyTypes.Load().(*customKeyRegistry)
+	if r == nil {
+		return &customKeyRegistry{}
+	}
+	return r
+}
+
+// RegisterKeyEncoder registers an encoder for a type which can't implement
+// KeyEncoder, e.g. the types of third-party packages. The encoder appends
+// the bytes which identify key to b and returns the extended slice, the key
+// is always of type typ.
+//
+// The types supported by default can't be registered, and the encoder
+// registered later replaces the previous one of the same type. It also
+// replaces the plan of a struct or array type, the keys converted by the
+// plan are still decodable, but they are different from the new keys.
+func RegisterKeyEncoder(typ reflect.Type, encoder func(b []byte, key interface{}) []byte) {
+	if typ == nil || encoder == nil {
+		panic("nil type or encoder")
+	}
+	switch reflect.Zero(typ).Interface().(type) {
+	case bool, uint8, int8, uint16, int16, uint32, int32, float32, uint64, int64,
+		float64, complex64, complex128, int, uint, string, []byte:
+		panic("can't register built-in key type")
+	}
+	registerCustomKeyType(typ, encoder, nil, nil)
+}
+
+// Returns the registered custom key type, the types which implement
+// KeyEncoder, the struct and array types with supported fields, the named
+// types of built-in kinds (e.g. type tenantID int64) and the pointers to
+// them are registered at the first time.
+//
+// A named type of built-in kind is converted with its type like a struct of
+// one field, so it never conflicts with the built-in type.
+func lookupCustomKeyType(typ reflect.Type) *customKeyType {
+	if typ == nil {
+		return nil
+	}
+	if t := loadCustomKeyRegistry().types[typ]; t != nil {
+		return t
+	}
+
+	if typ.Kind() == reflect.Ptr {
+		// A pointer is converted as the value it points to, even if the
+		// pointer implements KeyEncoder.
+		elem := typ.Elem()
+		t := lookupCustomKeyType(elem)
+		if t == nil || t.typ != elem {
+			// Not supported, or elem is a pointer too.
+			return nil
+		}
+		return registerCustomKeyType(elem, nil, nil, typ)
+	}
+	if implementsKeyEncoder(typ) {
+		return registerCustomKeyType(typ, nil, nil, nil)
+	}
+	if typ.Kind() == reflect.Struct || typ.Kind() == reflect.Array ||
+		typ.Name() != "" && builtinKeyTypes[typ.Kind()] != nil {
+		if plan := newKeyPlan(typ); plan != nil {
+			return registerCustomKeyType(typ, nil, plan, nil)
+		}
+	}
+	return nil
+}
+
+var keyEncoderType = reflect.TypeOf((*KeyEncoder)(nil)).Elem()
+
+// Reports whether typ or the pointer to it implements KeyEncoder, the
+// AppendCacheKey of pointer receiver is called with a copy of key.
+func implementsKeyEncoder(typ reflect.Type) bool {
+	return typ.Implements(keyEncoderType) || reflect.PtrTo(typ).Implements(keyEncoderType)
+}
+
+// Returns the custom key type of name, nil if it is not registered.
+func lookupCustomKeyTypeByName(name string) *customKeyType {
+	return loadCustomKeyRegistry().names[name]
+}
+
+// Returns the name of type, the package path is included to make it unique.
+func customKeyTypeName(typ reflect.Type) string {
+	if typ.Name() != "" && typ.PkgPath() != "" {
+		return typ.PkgPath() + "." + typ.Name()
+	}
+	return typ.String()
+}
+
+// Register a custom key type with the encoder or the plan. If both of them
+// are nil, typ must be registered, and ptrTyp, the pointer to typ, is added
+// to share the registered type.
+func registerCustomKeyType(typ reflect.Type, encoder func(b []byte, key interface{}) []byte,
+	plan *keyPlan, ptrTyp reflect.Type) *customKeyType {
+	customKeyTypesLock.Lock()
+	defer customKeyTypesLock.Unlock()
+
+	r := loadCustomKeyRegistry()
+	old := r.types[typ]
+	if old != nil && encoder == nil && (ptrTyp == nil || r.types[ptrTyp] == old) {
+		// Registered by another goroutine.
+		return old
+	}
+
+	newR := &customKeyRegistry{
+		types: make(map[reflect.Type]*customKeyType, len(r.types)+1),
+		ids:   make(map[uint32]*customKeyType, len(r.ids)+1),
+		names: make(map[string]*customKeyType, len(r.names)+1),
+		plans: make(map[string]*customKeyType, len(r.plans)+1),
+	}
+	for k, v := range r.types {
+		newR.types[k] = v
+	}
+	for k, v := range r.ids {
+		newR.ids[k] = v
+	}
+	for k, v := range r.names {
+		newR.names[k] = v
+	}
+	for k, v := range r.plans {
+		newR.plans[k] = v
+	}
+	if old != nil && encoder == nil {
+		// Only add the pointer type.
+		newR.types[ptrTyp] = old
+		customKeyTypes.Store(newR)
+		return old
+	}
+
+	// The old ids are never removed, so the keys converted by them can
+	// still be decoded.
+	id := uint32(len(r.ids) + 1)
+	if old != nil && old.plan == nil {
+		// Replace an encoder, keep the id, then the keys in caches are
+		// still valid.
+		id = old.id
+	}
+	t := &customKeyType{id: id, name: customKeyTypeName(typ), typ: typ, encoder: encoder, plan: plan}
+	for k, v := range r.types {
+		if old != nil && v == old {
+			// The pointer types follow the new one.
+			newR.types[k] = t
+		}
+	}
+	newR.types[typ] = t
+	if ptrTyp != nil {
+		newR.types[ptrTyp] = t
+	}
+	newR.ids[id] = t
+	newR.names[t.name] = t
+	if plan != nil {
+		newR.plans[t.name] = t
+	}
+	customKeyTypes.Store(newR)
+	return t
+}
+
+// Append a key of custom type to b, the layout is tag, type id, length of
+// data and data. The returned bool is false if the type is not supported.
+func appendCustomKey(b []byte, v interface{}) ([]byte, bool) {
+	t := lookupCustomKeyType(reflect.TypeOf(v))
+	if t == nil {
+		return b, false
+	}
+	if t.plan != nil {
+		b, err := appendStructKey(b, t, v)
+		return b, err == nil
+	}
+
+	if v = t.encoderKey(v); v == nil {
+		return b, false
+	}
+
+	b = append(b, customKeyTag, (*(*[4]byte)(unsafe.Pointer(&t.id)))[0], (*(*[4]byte)(unsafe.Pointer(&t.id)))[1],
+		(*(*[4]byte)(unsafe.Pointer(&t.id)))[2], (*(*[4]byte)(unsafe.Pointer(&t.id)))[3])
+	// Reserve the space of length, it is filled after appending data.
+	start := len(b)
+	var bLen int
+	b = append(b, (*(*[bit / 8]byte)(unsafe.Pointer(&bLen)))[:]...)
+	b = t.encode(b, v)
+	bLen = len(b) - start - bit/8
+	copy(b[start:], (*(*[bit / 8]byte)(unsafe.Pointer(&bLen)))[:])
+	return b, true
+}
+
+// Returns the key passed to the encoder or KeyEncoder, a pointer key is
+// dereferenced since the encoder only accepts keys of its type, then the
+// pointer and the value it points to are converted to the same bytes. It
+// returns nil for nil pointers.
+func (t *customKeyType) encoderKey(v interface{}) interface{} {
+	typ := reflect.TypeOf(v)
+	if typ.Kind() == reflect.Ptr && keyPointer(v) == nil {
+		// Also for a registered pointer type, the encoder never sees nil.
+		return nil
+	}
+	if typ == t.typ {
+		return v
+	}
+	if _, ok := v.(KeyEncoder); ok && t.encoder == nil {
+		// Call AppendCacheKey via the pointer, no copy is needed.
+		return v
+	}
+	return reflect.ValueOf(v).Elem().Interface()
+}
+
+// Append the key converted by the encoder or KeyEncoder to b.
+func (t *customKeyType) encode(b []byte, v interface{}) []byte {
+	if t.encoder != nil {
+		return t.encoder(b, v)
+	}
+	if e, ok := v.(KeyEncoder); ok {
+		return e.AppendCacheKey(b)
+	}
+	// AppendCacheKey has a pointer receiver.
+	p := reflect.New(t.typ)
+	p.Elem().Set(reflect.ValueOf(v))
+	return p.Interface().(KeyEncoder).AppendCacheKey(b)
+}
+
+// Decode a key of custom type from b, which is the bytes following the tag.
+// The returned int is the number of used bytes.
+func decodeCustomKey(b []byte) (CustomKey, int, error) {
+	if len(b) < 4+bit/8 {
+		return CustomKey{}, 0, errShortBytes
+	}
+	var id uint32
+	copy((*[4]byte)(unsafe.Pointer(&id))[:], b)
+	var bLen int
+	copy((*[bit / 8]byte)(unsafe.Pointer(&bLen))[:], b[4:])
+	b = b[4+bit/8:]
+	if bLen < 0 || len(b) < bLen {
+		return CustomKey{}, 0, errShortBytes
+	}
+	t := loadCustomKeyRegistry().ids[id]
+	if t == nil {
+		return CustomKey{}, 0, errUnknownType
+	}
+	return CustomKey{Type: t.typ, Data: append([]byte{}, b[:bLen]...)}, 4 + bit/8 + bLen, nil
+}
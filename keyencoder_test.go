@@ -0,0 +1,181 @@
+package lrucache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testUserID struct {
+	tenant string
+	id     uint64
+}
+
+func (k testUserID) AppendCacheKey(b []byte) []byte {
+	b = append(b, k.tenant...)
+	return append(b, byte(k.id), byte(k.id>>8))
+}
+
+type testOrderID struct {
+	tenant string
+	id     uint64
+}
+
+func (k testOrderID) AppendCacheKey(b []byte) []byte {
+	b = append(b, k.tenant...)
+	return append(b, byte(k.id), byte(k.id>>8))
+}
+
+type testSessionID struct {
+	id uint64
+}
+
+func (k *testSessionID) AppendCacheKey(b []byte) []byte {
+	return append(b, byte(k.id), byte(k.id>>8))
+}
+
+func init() {
+	RegisterKeyEncoder(reflect.TypeOf(time.Time{}), func(b []byte, key interface{}) []byte {
+		return key.(time.Time).AppendFormat(b, time.RFC3339Nano)
+	})
+}
+
+func BenchmarkInterfaceToBytesWithBufCustom(b *testing.B) {
+	buf := make([]byte, 0, 128)
+	key := testUserID{"tenant", 1}
+	for i := 0; i < b.N; i++ {
+		interfaceToBytesWithBuf(buf, key)
+	}
+}
+
+func TestKeyEncoder(t *testing.T) {
+	l := New(64)
+	l.Set(testUserID{"a", 1}, 1)
+	l.Set(testOrderID{"a", 1}, 2)
+	l.MSet(testUserID{"a", 2}, 1, 3)
+
+	if v, ok := l.Get(testUserID{"a", 1}); v != 1 || !ok {
+		t.Error("get custom key error")
+	}
+	if v, ok := l.Get(testOrderID{"a", 1}); v != 2 || !ok {
+		t.Error("custom keys of different types conflict")
+	}
+	if v, ok := l.MGet(testUserID{"a", 2}, 1); v != 3 || !ok {
+		t.Error("get multi custom keys error")
+	}
+	if _, ok := l.Get(testUserID{"a", 3}); ok {
+		t.Error("get non-existent custom key error")
+	}
+
+	// Custom keys are length-prefixed
+	if string(interfaceToBytes(testUserID{"a", 1}, testUserID{"b", 1})) ==
+		string(interfaceToBytes(testUserID{"a\x01\x00b", 1})) {
+		t.Error("length prefix error")
+	}
+
+	// Pointer keys are converted as the values, the encoder may have a
+	// pointer receiver
+	for _, encoding := range []KeyEncoding{NativeEncoding, CanonicalEncoding} {
+		l := New(8, WithKeyEncoding(encoding))
+		l.Set(testUserID{"a", 1}, 1)
+		l.Set(&testSessionID{1}, 2)
+		if v, ok := l.Get(&testUserID{"a", 1}); v != 1 || !ok {
+			t.Error("get custom key via pointer error")
+		}
+		if v, ok := l.Get(testSessionID{1}); v != 2 || !ok {
+			t.Error("get custom key of pointer receiver error")
+		}
+		if k := l.Keys()[0][0].(CustomKey); k.Type != reflect.TypeOf(testSessionID{}) || string(k.Data) != "\x01\x00" {
+			t.Error("decode custom key of pointer receiver error", k)
+		}
+	}
+
+	// Nil pointers are not supported, the encoders are not called
+	type handle struct{ id int }
+	restoreKeyRegistry(t)
+	RegisterKeyEncoder(reflect.TypeOf(&handle{}), func(b []byte, key interface{}) []byte {
+		return append(b, byte(key.(*handle).id))
+	})
+	for _, k := range []interface{}{(*testUserID)(nil), (*testSessionID)(nil), (*handle)(nil)} {
+		if _, err := l.TrySet(k, 1); err != ErrUnsupportedKey {
+			t.Error("nil pointer key error", k)
+		}
+	}
+	if _, err := l.TrySet(&handle{1}, 1); err != nil {
+		t.Error("registered pointer key error")
+	}
+
+	// Registered type
+	now := time.Now()
+	l.Set(now, "now")
+	if v, ok := l.Get(now); v != "now" || !ok {
+		t.Error("get registered key error")
+	}
+
+	// Decode
+	args, err := bytesToInterfaces(interfaceToBytes(1, testUserID{"a", 1}, now))
+	if err != nil || len(args) != 3 || args[0] != 1 {
+		t.Fatal("decode custom key error")
+	}
+	if k := args[1].(CustomKey); k.Type != reflect.TypeOf(testUserID{}) || string(k.Data) != "a\x01\x00" {
+		t.Error("decode custom key error")
+	}
+	if k := args[2].(CustomKey); k.Type != reflect.TypeOf(now) || string(k.Data) != now.Format(time.RFC3339Nano) {
+		t.Error("decode registered key error")
+	}
+	b := interfaceToBytes(testUserID{"a", 1})
+	for i := 1; i < len(b); i++ {
+		if _, err := bytesToInterfaces(b[:i]); err != errShortBytes {
+			t.Error("decode short custom key error", i)
+		}
+	}
+	b[1] = 0xff
+	if _, err := bytesToInterfaces(b); err != errUnknownType {
+		t.Error("decode unknown custom key error")
+	}
+
+	// Unsupported types
+	for _, v := range []interface{}{nil, []int{}, map[int]int{}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("unsupported type error")
+				}
+			}()
+			interfaceToBytes(v)
+		}()
+	}
+}
+
+func TestRegisterKeyEncoder(t *testing.T) {
+	restoreKeyRegistry(t)
+	for _, typ := range []reflect.Type{nil, reflect.TypeOf(1), reflect.TypeOf([]byte{})} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("register invalid type error")
+				}
+			}()
+			RegisterKeyEncoder(typ, func(b []byte, key interface{}) []byte {
+				return b
+			})
+		}()
+	}
+
+	// Replace the encoder of a type
+	type point struct{ x, y int8 }
+	RegisterKeyEncoder(reflect.TypeOf(point{}), func(b []byte, key interface{}) []byte {
+		return append(b, byte(key.(point).x))
+	})
+	id := lookupCustomKeyType(reflect.TypeOf(point{})).id
+	if string(interfaceToBytes(point{1, 2})) != string(interfaceToBytes(point{1, 3})) {
+		t.Error("registered encoder error")
+	}
+	RegisterKeyEncoder(reflect.TypeOf(point{}), func(b []byte, key interface{}) []byte {
+		return append(b, byte(key.(point).x), byte(key.(point).y))
+	})
+	if string(interfaceToBytes(point{1, 2})) == string(interfaceToBytes(point{1, 3})) ||
+		lookupCustomKeyType(reflect.TypeOf(point{})).id != id {
+		t.Error("replace registered encoder error")
+	}
+}
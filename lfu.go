@@ -0,0 +1,144 @@
+package lrucache
+
+import "unsafe"
+
+// LFU returns a policy which eliminates the least frequently used key, the
+// least recently used one is eliminated if there are several. All methods
+// of the policy are O(1).
+//
+// The frequencies never decay, so the keys which were hot long ago may stay
+// in cache forever, use LFUWithAging to avoid it.
+func LFU() Policy {
+	return &lfuPolicy{}
+}
+
+// LFUWithAging returns a function which creates LFU policies, the
+// frequencies of all keys are halved every period accesses, so the stale
+// popularity decays. It can be passed to WithPolicy directly.
+//
+// Aging walks all keys, so the period should not be less than the size of
+// cache to keep the amortized cost of access O(1).
+func LFUWithAging(period int) func() Policy {
+	return func() Policy {
+		return &lfuPolicy{period: period}
+	}
+}
+
+type lfuPolicy struct {
+	// The buckets ordered by frequency, the head has the lowest one.
+	head *lfuBucket
+	// Frequencies are halved every period accesses, zero means never.
+	period   int
+	accesses int
+}
+
+// The keys of the same frequency ordered by access time.
+type lfuBucket struct {
+	// It must be the first field, see bucketOf.
+	entries    entryList
+	freq       uint64
+	prev, next *lfuBucket
+}
+
+// Returns the bucket which holds e, the list of e is the first field of the
+// bucket, so they have the same address.
+func bucketOf(e *Entry) *lfuBucket {
+	return (*lfuBucket)(unsafe.Pointer(e.list))
+}
+
+func (p *lfuPolicy) OnInsert(e *Entry) {
+	b := p.head
+	if b == nil || b.freq != 1 {
+		b = p.insertBucket(nil, 1)
+	}
+	b.entries.pushBack(e)
+}
+
+func (p *lfuPolicy) OnAccess(e *Entry) {
+	b := bucketOf(e)
+	next := b.next
+	if next == nil || next.freq != b.freq+1 {
+		next = p.insertBucket(b, b.freq+1)
+	}
+	b.entries.remove(e)
+	next.entries.pushBack(e)
+	if b.entries.len == 0 {
+		p.removeBucket(b)
+	}
+
+	if p.period > 0 {
+		p.accesses++
+		if p.accesses >= p.period {
+			p.accesses = 0
+			p.age()
+		}
+	}
+}
+
+func (p *lfuPolicy) OnRemove(e *Entry) {
+	b := bucketOf(e)
+	b.entries.remove(e)
+	if b.entries.len == 0 {
+		p.removeBucket(b)
+	}
+}
+
+func (p *lfuPolicy) Victim() *Entry {
+	if p.head == nil {
+		return nil
+	}
+	return p.head.entries.head
+}
+
+// Insert a new bucket of freq after prev, or as the head if prev is nil.
+func (p *lfuPolicy) insertBucket(prev *lfuBucket, freq uint64) *lfuBucket {
+	b := &lfuBucket{freq: freq, prev: prev}
+	if prev == nil {
+		b.next = p.head
+		p.head = b
+	} else {
+		b.next = prev.next
+		prev.next = b
+	}
+	if b.next != nil {
+		b.next.prev = b
+	}
+	return b
+}
+
+func (p *lfuPolicy) removeBucket(b *lfuBucket) {
+	if b.prev == nil {
+		p.head = b.next
+	} else {
+		b.prev.next = b.next
+	}
+	if b.next != nil {
+		b.next.prev = b.prev
+	}
+}
+
+// Halve the frequencies of all keys, the buckets which get the same
+// frequency are merged, and the keys of the lower frequency become older.
+func (p *lfuPolicy) age() {
+	var last *lfuBucket
+	for b := p.head; b != nil; {
+		next := b.next
+		freq := b.freq / 2
+		if freq == 0 {
+			freq = 1
+		}
+		if last != nil && last.freq == freq {
+			for e := b.entries.head; e != nil; {
+				n := e.lnext
+				b.entries.remove(e)
+				last.entries.pushBack(e)
+				e = n
+			}
+			p.removeBucket(b)
+		} else {
+			b.freq = freq
+			last = b
+		}
+		b = next
+	}
+}
@@ -0,0 +1,119 @@
+package lrucache
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Check the buckets are ordered and hold all keys of cache.
+func checkLFU(t *testing.T, l *LRUCache) {
+	t.Helper()
+	p := l.policy.(*lfuPolicy)
+	n := 0
+	for b := p.head; b != nil; b = b.next {
+		if b.entries.len == 0 || b.next != nil && (b.next.freq <= b.freq || b.next.prev != b) {
+			t.Fatal("buckets error")
+		}
+		for e := b.entries.head; e != nil; e = e.lnext {
+			if bucketOf(e) != b || l.m[e.key] != e {
+				t.Fatal("bucket entries error")
+			}
+			n++
+		}
+	}
+	if n != len(l.m) {
+		t.Fatal("bucket length error", n, len(l.m))
+	}
+}
+
+func TestLFU(t *testing.T) {
+	l := New(3, WithPolicy(LFU))
+	l.Set(1, 1)
+	l.Set(2, 2)
+	l.Set(3, 3)
+	l.Get(1)
+	l.Get(1)
+	l.Get(2)
+	checkLFU(t, l)
+
+	// A scan of cold keys doesn't flush the hot keys
+	for i := 10; i < 20; i++ {
+		l.Set(i, i)
+		checkLFU(t, l)
+	}
+	if !l.Contains(1) || !l.Contains(2) || !l.Contains(19) || l.Len() != 3 {
+		t.Error("LFU victim error")
+	}
+
+	// The least recently used one is eliminated among the same frequency
+	l.Get(19)
+	l.Set(20, 20)
+	if l.Contains(2) || !l.Contains(19) || !l.Contains(20) {
+		t.Error("LFU tie error")
+	}
+	checkLFU(t, l)
+
+	l.Delete(1)
+	checkLFU(t, l)
+	l.Purge()
+	if l.policy.(*lfuPolicy).head != nil {
+		t.Error("LFU purge error")
+	}
+}
+
+func TestLFUWithAging(t *testing.T) {
+	l := New(3, WithPolicy(LFUWithAging(8)))
+	l.Set(1, 1)
+	for i := 0; i < 6; i++ {
+		l.Get(1)
+	}
+	l.Set(2, 2)
+	l.Get(2)
+	// The 8th access halves the frequencies, 1: 7 -> 3, 2: 3 -> 1
+	l.Get(2)
+	checkLFU(t, l)
+	p := l.policy.(*lfuPolicy)
+	if p.head.freq != 1 || p.head.next.freq != 3 || p.head.next.next != nil {
+		t.Error("aging error")
+	}
+
+	// Stale popularity decays
+	for _, c := range []struct {
+		newPolicy func() Policy
+		evicted   int
+	}{{LFU, 2}, {LFUWithAging(4), 1}} {
+		l = New(2, WithPolicy(c.newPolicy))
+		l.Set(1, 1)
+		for i := 0; i < 20; i++ {
+			l.Get(1)
+		}
+		l.Set(2, 2)
+		for i := 0; i < 10; i++ {
+			l.Get(2)
+		}
+		l.Set(3, 3)
+		if l.Contains(c.evicted) || !l.Contains(3) || l.Len() != 2 {
+			t.Error("aging victim error", c.evicted)
+		}
+		checkLFU(t, l)
+	}
+}
+
+func TestLFU_Random(t *testing.T) {
+	l := New(64, WithPolicy(LFUWithAging(100)))
+	for i := 0; i < 10000; i++ {
+		k := rand.Intn(128)
+		switch rand.Intn(4) {
+		case 0:
+			l.Delete(k)
+		case 1:
+			l.Set(k, k)
+		default:
+			l.Get(k)
+		}
+		if i%100 == 0 {
+			checkLFU(t, l)
+		}
+	}
+	checkLFU(t, l)
+}
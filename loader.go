@@ -0,0 +1,118 @@
+package lrucache
+
+import (
+	"errors"
+	"github.com/ZYunH/sbconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrLoaderPanic is returned by GetOrLoad and MGetOrLoad to the goroutines
+// waiting for a loader which panics.
+var ErrLoaderPanic = errors.New("loader panic")
+
+// A running loader, other goroutines loading the same key wait for it.
+type call struct {
+	wg    sync.WaitGroup
+	value interface{}
+	err   error
+}
+
+// Get value via a single key, if the key is not in cache, the loader is
+// called and the value returned by loader is set to cache unless it returns
+// an error.
+//
+// Only one loader runs for the same key at a time, other goroutines wait
+// for its result. The loader is called without holding the lock of cache.
+// ErrUnsupportedKey is returned instead of panic if the type of key is not
+// supported.
+func (c *LRUCache) GetOrLoad(key interface{}, loader func() (interface{}, error)) (value interface{}, err error) {
+	c.lock.Lock()
+	k, err := c.tryKey(key)
+	if err != nil {
+		c.lock.Unlock()
+		return nil, err
+	}
+	return c.getOrLoad(k, loader)
+}
+
+// Get value via multi-keys, if the keys are not in cache, the loader is
+// called, see GetOrLoad for the details. ErrTooFewArgs is returned if there
+// is no key, and ErrUnsupportedKey is returned if the type of any key is not
+// supported.
+func (c *LRUCache) MGetOrLoad(loader func() (interface{}, error), keys ...interface{}) (value interface{}, err error) {
+	if len(keys) == 0 {
+		return nil, ErrTooFewArgs
+	}
+	c.lock.Lock()
+	key, err := c.tryKey(keys...)
+	if err != nil {
+		c.lock.Unlock()
+		return nil, err
+	}
+	return c.getOrLoad(key, loader)
+}
+
+// Get value via a single string or load it, the lock must be held by
+// caller and it is released before return, even if get panics.
+func (c *LRUCache) getOrLoad(k string, loader func() (interface{}, error)) (interface{}, error) {
+	locked := true
+	defer func() {
+		if locked {
+			c.lock.Unlock()
+		}
+	}()
+	if value, ok := c.get(k); ok {
+		return value, nil
+	}
+
+	if cl := c.calls[k]; cl != nil {
+		// Another goroutine is loading this key.
+		locked = false
+		c.lock.Unlock()
+		cl.wg.Wait()
+		return cl.value, cl.err
+	}
+
+	cl := &call{}
+	cl.wg.Add(1)
+	k = sbconv.DeepCopyString(k)
+	if c.calls == nil {
+		c.calls = make(map[string]*call)
+	}
+	c.calls[k] = cl
+	locked = false
+	c.lock.Unlock()
+
+	c.load(k, cl, loader)
+	return cl.value, cl.err
+}
+
+func (c *LRUCache) load(k string, cl *call, loader func() (interface{}, error)) {
+	normalReturn := false
+	start := time.Now()
+	defer func() {
+		if !normalReturn {
+			cl.value, cl.err = nil, ErrLoaderPanic
+		}
+		atomic.AddInt64(&c.loadTime, int64(time.Since(start)))
+		if cl.err == nil {
+			atomic.AddInt64(&c.loadSuccesses, 1)
+		} else {
+			atomic.AddInt64(&c.loadFailures, 1)
+		}
+		// The waiters are woken up and the lock is released even if set
+		// panics.
+		defer cl.wg.Done()
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		delete(c.calls, k)
+		if cl.err == nil {
+			c.set(k, cl.value, 0, c.weigh(k, cl.value))
+		}
+	}()
+
+	cl.value, cl.err = loader()
+	normalReturn = true
+}
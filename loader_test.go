@@ -0,0 +1,129 @@
+package lrucache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLRUCache_GetOrLoad(t *testing.T) {
+	l := New(64)
+	loads := 0
+	loader := func() (interface{}, error) {
+		loads++
+		return "value", nil
+	}
+
+	v, err := l.GetOrLoad(1, loader)
+	if v != "value" || err != nil || loads != 1 {
+		t.Error("load error")
+	}
+	v, err = l.GetOrLoad(1, loader)
+	if v != "value" || err != nil || loads != 1 {
+		t.Error("get loaded value error")
+	}
+
+	v, err = l.MGetOrLoad(loader, 1, 2)
+	if v != "value" || err != nil || loads != 2 {
+		t.Error("multi-keys load error")
+	}
+	if v, ok := l.MGet(1, 2); v != "value" || !ok {
+		t.Error("multi-keys load error")
+	}
+
+	// No key or unsupported keys, nothing is loaded
+	for _, c := range []Cache{New(3, WithPolicy(SLRU(0.5))), NewSharded(2, 4)} {
+		if v, err := c.MGetOrLoad(loader); v != nil || err != ErrTooFewArgs || c.Len() != 0 || loads != 2 {
+			t.Error("load without key error")
+		}
+		if v, err := c.GetOrLoad([]int{}, loader); v != nil || err != ErrUnsupportedKey || loads != 2 {
+			t.Error("load unsupported key error")
+		}
+		if v, err := c.MGetOrLoad(loader, 1, []int{}); v != nil || err != ErrUnsupportedKey || loads != 2 {
+			t.Error("load unsupported keys error")
+		}
+		c.Set(1, 1) // The lock is released
+	}
+
+	// The value is not set if loader returns an error
+	errLoad := errors.New("load error")
+	v, err = l.GetOrLoad(3, func() (interface{}, error) {
+		return nil, errLoad
+	})
+	if v != nil || err != errLoad {
+		t.Error("load error")
+	}
+	if _, ok := l.Get(3); ok {
+		t.Error("set value while load error")
+	}
+
+	// Loader panics
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("loader panic error")
+			}
+		}()
+		l.GetOrLoad(4, func() (interface{}, error) {
+			panic("panic")
+		})
+	}()
+	if len(l.calls) != 0 {
+		t.Error("clean loader error")
+	}
+	if v, err := l.GetOrLoad(4, loader); v != "value" || err != nil {
+		t.Error("load after panic error")
+	}
+}
+
+func TestLRUCache_GetOrLoadConcurrent(t *testing.T) {
+	l := New(64)
+	var loads int32
+	start := make(chan struct{})
+	loader := func() (interface{}, error) {
+		atomic.AddInt32(&loads, 1)
+		<-start
+		return "value", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := l.GetOrLoad("key", loader)
+			if v != "value" || err != nil {
+				t.Error("concurrent load error")
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond * 10)
+
+	// The cache can be used while loading
+	l.Set(1, 1)
+	close(start)
+	wg.Wait()
+	if loads != 1 {
+		t.Error("duplicate load error", loads)
+	}
+
+	// Waiters receive ErrLoaderPanic
+	start = make(chan struct{})
+	go func() {
+		defer func() { recover() }()
+		l.GetOrLoad("panic", func() (interface{}, error) {
+			<-start
+			panic("panic")
+		})
+	}()
+	time.Sleep(time.Millisecond * 10)
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		close(start)
+	}()
+	if _, err := l.GetOrLoad("panic", loader); err != ErrLoaderPanic {
+		t.Error("wait panicked loader error")
+	}
+}
@@ -1,108 +1,464 @@
 package lrucache
 
 import (
+	"errors"
 	"github.com/ZYunH/sbconv"
+	"math"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
-type lruCache struct {
-	m       map[string]*node
-	root    *node
+// Cache is the interface implemented by caches in this package, it can be
+// used to swap in test doubles and alternative implementations.
+type Cache interface {
+	Set(key, value interface{}) (isRemove bool)
+	Get(key interface{}) (value interface{}, ok bool)
+	MSet(kvs ...interface{}) (isRemove bool)
+	SetWithTTL(key, value interface{}, ttl time.Duration) (isRemove bool)
+	MSetWithTTL(ttl time.Duration, kvs ...interface{}) (isRemove bool)
+	SetWithCost(key, value interface{}, cost int64) (isRemove bool)
+	MSetWithCost(cost int64, kvs ...interface{}) (isRemove bool)
+	MGet(keys ...interface{}) (value interface{}, ok bool)
+	TrySet(key, value interface{}) (isRemove bool, err error)
+	TryGet(key interface{}) (value interface{}, ok bool, err error)
+	TryMSet(kvs ...interface{}) (isRemove bool, err error)
+	TryMGet(keys ...interface{}) (value interface{}, ok bool, err error)
+	Peek(key interface{}) (value interface{}, ok bool)
+	MPeek(keys ...interface{}) (value interface{}, ok bool)
+	Contains(key interface{}) bool
+	MContains(keys ...interface{}) bool
+	GetOrLoad(key interface{}, loader func() (interface{}, error)) (value interface{}, err error)
+	MGetOrLoad(loader func() (interface{}, error), keys ...interface{}) (value interface{}, err error)
+	Delete(key interface{}) (isDelete bool)
+	MDelete(keys ...interface{}) (isDelete bool)
+	Range(fn func(key []interface{}, value interface{}) bool)
+	Keys() [][]interface{}
+	Purge()
+	Resize(maxSize int) (evicted int)
+	Len() int
+	Cap() int
+	Cost() int64
+	Bytes() int64
+	Info() (hits, misses int64)
+	HitRatio() float64
+	Stats() Stats
+	ResetStats()
+	Close()
+}
+
+var _ Cache = (*LRUCache)(nil)
+
+var (
+	// ErrUnsupportedKey is returned if the type of a key is not supported.
+	ErrUnsupportedKey = errors.New("unsupported key type")
+	// ErrTooFewArgs is returned if there is no key or value for MSet, or no
+	// key for MGetOrLoad.
+	ErrTooFewArgs = errors.New("at least one key and one value")
+	// ErrInvalidSize is returned if maxSize is not greater than 0.
+	ErrInvalidSize = errors.New("maxSize must be greater than 0")
+	// ErrInvalidCost is returned if maxCost is not greater than 0 or a cost
+	// is negative.
+	ErrInvalidCost = errors.New("maxCost must be greater than 0 and cost must not be negative")
+)
+
+// LRUCache is a concurrent-safe LRU cache, use New to create one.
+type LRUCache struct {
+	// The counters are placed first to keep them 64-bit aligned, which is
+	// required by the atomic operations on 32-bit platforms.
+	counters
+
+	m       map[string]*Entry
+	root    *Entry
 	maxSize int
-	hits    int64
-	misses  int64
+	// The max total cost of keys, zero means unlimited.
+	maxCost int64
+	cost    int64
+	// The max estimated memory size of keys, zero means unlimited.
+	maxBytes int64
+	bytes    int64
+
+	// The encoding of keys in map.
+	encoding KeyEncoding
+	onEvict  func(key []interface{}, value interface{}, reason EvictReason)
+	weigher  func(key []interface{}, value interface{}) int64
+	// The eviction policy, nil means LRU which is handled by the linked
+	// list directly.
+	policy    Policy
+	newPolicy func() Policy
+	// The running loaders of GetOrLoad and MGetOrLoad.
+	calls map[string]*call
 
 	lock        sync.Mutex
 	_buf        []byte
-	_bufNodePtr *node
+	_bufNodePtr *Entry
+
+	// The next node to be checked by the janitor, nil if no sweep is running.
+	cursor    *Entry
+	stop      chan struct{}
+	closeOnce sync.Once
 }
 
-type node struct {
+// Entry is a key and its value in cache, it identifies the key in the
+// methods of Policy. It is read-only for policies, and it is reused by cache
+// after OnRemove, so its methods must not be called after that.
+type Entry struct {
 	key   string
 	value interface{}
-	prev  *node
-	next  *node
+	prev  *Entry
+	next  *Entry
+	// The expiration time in unix nanoseconds, zero means never expires.
+	expire int64
+	cost   int64
+	size   int64
+	// The list of policy which holds the entry, see entryList.
+	list         *entryList
+	lprev, lnext *Entry
+}
+
+// Key returns the converted key, which is unique for every key in cache, so
+// a policy can remember the key after it leaves cache, e.g. the ghost lists
+// of ARC. It is not the key passed to Set, see Range for the decoded keys.
+func (e *Entry) Key() string {
+	return e.key
+}
+
+// Value returns the value of key.
+func (e *Entry) Value() interface{} {
+	return e.value
+}
+
+// Cost returns the cost of key, see NewWeighted.
+func (e *Entry) Cost() int64 {
+	return e.cost
+}
+
+// Size returns the estimated memory size of key and its value, see Sizer.
+func (e *Entry) Size() int64 {
+	return e.size
+}
+
+// EvictReason indicates why a key leaves the cache.
+type EvictReason uint8
+
+const (
+	// EvictCapacity means the key is eliminated since the cache is full.
+	EvictCapacity EvictReason = iota
+	// EvictDeleted means the key is deleted explicitly.
+	EvictDeleted
+	// EvictExpired means the key is expired.
+	EvictExpired
+	// EvictOverwritten means the value is replaced by a new one.
+	EvictOverwritten
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case EvictCapacity:
+		return "capacity"
+	case EvictDeleted:
+		return "deleted"
+	case EvictExpired:
+		return "expired"
+	case EvictOverwritten:
+		return "overwritten"
+	}
+	return "unknown"
 }
 
 // Indicates 64-bit or 32-bit system.
 const bit = 32 << (^uint(0) >> 63)
 
-// New creates a new LRU cache with max size.
-func New(maxSize int) *lruCache {
+// New creates a new LRU cache with max size, panic will occur if maxSize is
+// not greater than 0.
+func New(maxSize int, opts ...Option) *LRUCache {
+	c, err := NewWithOptions(maxSize, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// NewWithOptions creates a new LRU cache with max size, it returns an error
+// instead of panic if the arguments are invalid.
+func NewWithOptions(maxSize int, opts ...Option) (*LRUCache, error) {
 	if maxSize <= 0 {
-		panic("maxSize must be greater than 0")
+		return nil, ErrInvalidSize
+	}
+	return newCache(maxSize, 0, newOptions(opts)), nil
+}
+
+// NewWeighted creates a new LRU cache with max total cost instead of max
+// size, the oldest keys are eliminated until the total cost fits after
+// setting a key, but a key whose cost is greater than maxCost is eliminated
+// at once and the other keys are kept. Panic will occur if maxCost is not
+// greater than 0.
+//
+// The cost of a key is 1 by default, use SetWithCost, MSetWithCost or the
+// WithWeigher option to specify it.
+func NewWeighted(maxCost int64, opts ...Option) *LRUCache {
+	if maxCost <= 0 {
+		panic(ErrInvalidCost)
 	}
-	root := &node{}
+	return newCache(math.MaxInt, maxCost, newOptions(opts))
+}
+
+func newCache(maxSize int, maxCost int64, o *options) *LRUCache {
+	root := &Entry{}
 	root.next = root
 	root.prev = root
-	return &lruCache{m: make(map[string]*node, maxSize), root: root, _buf: make([]byte, 0, 128), maxSize: maxSize}
+	c := &LRUCache{root: root, _buf: make([]byte, 0, 128), maxSize: maxSize, maxCost: maxCost}
+	c.m = make(map[string]*Entry, c.mapSizeHint())
+	c.maxBytes = o.maxBytes
+	c.onEvict = o.onEvict
+	c.weigher = o.weigher
+	if o.newPolicy != nil {
+		c.newPolicy = o.newPolicy
+		c.policy = o.newPolicy()
+		if maxCost == 0 {
+			c.setPolicyCapacity(maxSize)
+		}
+	}
+	c.encoding = o.keyEncoding
+	if o.janitorInterval > 0 {
+		c.stop = make(chan struct{})
+		go c.janitor(o.janitorInterval)
+	}
+	return c
 }
 
-// Set single key and value.
+// Returns the initial size of map, the max size of a weighted cache is
+// unlimited so nothing is preallocated.
+func (c *LRUCache) mapSizeHint() int {
+	if c.maxCost > 0 {
+		return 0
+	}
+	return c.maxSize
+}
+
+// Convert key arguments to a pseudo-string in buffer, the lock must be held.
+// Panic will occur if any argument is not supported.
 //
-// The returned value indicates whether a key is eliminated from cache.
-func (c *lruCache) Set(key, value interface{}) (isRemove bool) {
-	c.lock.Lock()
-	k := sbconv.BytesToString(interfaceToBytesWithBuf(c._buf, key))
+// The callers release the lock with defer, since the key conversion, the
+// eviction callback, the weigher and Sizer may panic while holding the lock,
+// then the cache is still usable after recovering.
+func (c *LRUCache) key(args ...interface{}) string {
+	k, err := c.tryKey(args...)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
+
+// Convert key arguments to a pseudo-string in buffer, the lock must be held.
+func (c *LRUCache) tryKey(args ...interface{}) (string, error) {
+	var b []byte
+	var err error
+	if c.encoding == CanonicalEncoding {
+		b, err = appendCanonicalKey(c._buf, args...)
+	} else {
+		b, err = appendKey(c._buf, args...)
+	}
+	k := sbconv.BytesToString(b)
 	// Grow buffer slice to preparing enough space for next conversion.
 	if cap(c._buf) < len(k) {
 		c._buf = make([]byte, 0, len(k))
 	}
-	isRemove = c.set(k, value)
-	c.lock.Unlock()
+	return k, err
+}
+
+// Set single key and value.
+//
+// The returned value indicates whether a key is eliminated from cache.
+func (c *LRUCache) Set(key, value interface{}) (isRemove bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	k := c.key(key)
+	isRemove = c.set(k, value, 0, c.weigh(k, value))
+	return isRemove
+}
+
+// Set single key and value with a time-to-live, the key will be seen as
+// non-existent after ttl. A non-positive ttl means the key never expires.
+//
+// The returned value indicates whether a key is eliminated from cache.
+func (c *LRUCache) SetWithTTL(key, value interface{}, ttl time.Duration) (isRemove bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	k := c.key(key)
+	isRemove = c.set(k, value, expireAt(ttl), c.weigh(k, value))
 	return isRemove
 }
 
+// Set single key and value with a cost, see NewWeighted for the details.
+// Panic will occur if cost is negative.
+//
+// The returned value indicates whether a key is eliminated from cache.
+func (c *LRUCache) SetWithCost(key, value interface{}, cost int64) (isRemove bool) {
+	if cost < 0 {
+		panic(ErrInvalidCost)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	k := c.key(key)
+	isRemove = c.set(k, value, 0, cost)
+	return isRemove
+}
+
+// Returns the expiration time of ttl, zero means never expires.
+func expireAt(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	return time.Now().UnixNano() + int64(ttl)
+}
+
 // Set value via single string
 //
 // The input string will be seen as a pseudo-string,
 // which actually is a byte slice in buffer, so if we want
 // to add this string to the map, a deep copy string is required.
-func (c *lruCache) set(k string, value interface{}) bool {
+func (c *LRUCache) set(k string, value interface{}, expire, cost int64) bool {
+	isRemove := false
+	size := entrySize(k, value)
+	if c.maxCost > 0 && cost > c.maxCost || c.maxBytes > 0 && size > c.maxBytes {
+		// The key never fits, eliminate it at once instead of the other
+		// keys, and drop its old value.
+		if n := c.m[k]; n != nil {
+			c.remove(n, EvictOverwritten)
+		}
+		c.evict(k, value, EvictCapacity)
+		return true
+	}
 	c._bufNodePtr = c.m[k]
 	if c._bufNodePtr == nil { // This means the k not in the map
 		k = sbconv.DeepCopyString(k)
+		if p, ok := c.policy.(insertPolicy); ok {
+			p.beforeInsert(k)
+		}
 		if len(c.m) < c.maxSize-1 {
 			// Cache is not full, insert a new node
-			_node := &node{}
+			_node := &Entry{}
 			_node.key = k
 			_node.value = value
+			_node.expire = expire
+			_node.cost = cost
+			_node.size = size
 			_node.next = c.root
 			_node.prev = c.root.prev
 			c.m[k] = _node
 
 			c.root.prev.next = _node
 			c.root.prev = _node
+			c._bufNodePtr = _node
 		} else {
+			if c.root.key != "" && c.policy != nil {
+				// The cache is full, eliminate the victim of policy, then
+				// the root becomes an empty node.
+				if c.maxBytes > 0 {
+					// The capacity may be lowered by the max bytes before.
+					c.setPolicyCapacity(c.maxSize)
+				}
+				if v := c.policy.Victim(); v != nil {
+					c.remove(v, EvictCapacity)
+					isRemove = true
+				}
+			}
 			// Cache is full, replace the oldest one with the new node,
 			// in this case, we just replace the original root with the
 			// new root, and make the original root.next become the new root.
+			// The root may be an empty node (its key is empty) if it has never
+			// been used or it has been deleted, nothing is eliminated in this case.
+			if c.root.key != "" {
+				isRemove = true
+				c.evict(c.root.key, c.root.value, EvictCapacity)
+				if c.policy != nil {
+					c.policy.OnRemove(c.root)
+				}
+			}
 			delete(c.m, c.root.key)
+			c.cost -= c.root.cost
+			c.bytes -= c.root.size
 			c.root.key = k
 			c.root.value = value
+			c.root.expire = expire
+			c.root.cost = cost
+			c.root.size = size
 			c.m[k] = c.root
+			c._bufNodePtr = c.root
 			c.root = c.root.next
-
-			return true
+		}
+		c.cost += cost
+		c.bytes += size
+		atomic.AddInt64(&c.sets, 1)
+		if c.policy != nil {
+			c.policy.OnInsert(c._bufNodePtr)
 		}
 	} else {
 		// Hits a key, we just update its value.
+		if c._bufNodePtr.expire != 0 && c._bufNodePtr.expire <= time.Now().UnixNano() {
+			c.evict(c._bufNodePtr.key, c._bufNodePtr.value, EvictExpired)
+		} else {
+			c.evict(c._bufNodePtr.key, c._bufNodePtr.value, EvictOverwritten)
+		}
 		c._bufNodePtr.value = value
+		c._bufNodePtr.expire = expire
+		c.cost += cost - c._bufNodePtr.cost
+		c._bufNodePtr.cost = cost
+		c.bytes += size - c._bufNodePtr.size
+		c._bufNodePtr.size = size
+		atomic.AddInt64(&c.updates, 1)
+	}
+
+	// Eliminate the oldest keys until the total cost and size fit.
+	overflow := false
+	for c.maxCost > 0 && c.cost > c.maxCost || c.maxBytes > 0 && c.bytes > c.maxBytes {
+		c.evictVictim()
+		overflow = true
+	}
+	if overflow {
+		// The cache is full with the keys left.
+		c.setPolicyCapacity(len(c.m))
+		isRemove = true
+	}
+	return isRemove
+}
+
+// Eliminate the victim of policy or the oldest key, the cache must not be
+// empty.
+func (c *LRUCache) evictVictim() {
+	if c.policy != nil {
+		if v := c.policy.Victim(); v != nil {
+			c.remove(v, EvictCapacity)
+			return
+		}
+	}
+	n := c.root
+	if n.key == "" {
+		// The root is an empty node.
+		n = n.next
 	}
-	return false
+	c.remove(n, EvictCapacity)
+}
+
+// Returns the cost of a key and its value, which is returned by the weigher
+// if it is configured, otherwise 1. A negative cost is seen as 0.
+func (c *LRUCache) weigh(k string, value interface{}) int64 {
+	if c.weigher != nil {
+		if cost := c.weigher(decodeKey(k, c.encoding), value); cost > 0 {
+			return cost
+		}
+		return 0
+	}
+	return 1
 }
 
 // Get value via a single key.
-func (c *lruCache) Get(key interface{}) (value interface{}, ok bool) {
+func (c *LRUCache) Get(key interface{}) (value interface{}, ok bool) {
 	c.lock.Lock()
-	k := sbconv.BytesToString(interfaceToBytesWithBuf(c._buf, key))
-	// Grow buffer slice to preparing enough space for next conversion.
-	if cap(c._buf) < len(k) {
-		c._buf = make([]byte, 0, len(k))
-	}
+	defer c.lock.Unlock()
+	k := c.key(key)
 	value, ok = c.get(k)
-	c.lock.Unlock()
 	return
 }
 
@@ -110,22 +466,36 @@ func (c *lruCache) Get(key interface{}) (value interface{}, ok bool) {
 //
 // Do not store the input string in any situations since it is just
 // a pseudo-string, which is actually a byte slice in shared buffer.
-func (c *lruCache) get(k string) (interface{}, bool) {
+func (c *LRUCache) get(k string) (interface{}, bool) {
 	c._bufNodePtr = c.m[k]
 
+	if c._bufNodePtr != nil && c._bufNodePtr.expire != 0 && c._bufNodePtr.expire <= time.Now().UnixNano() {
+		// Hits an expired key, remove it lazily and see it as non-existent.
+		atomic.AddInt64(&c.expired, 1)
+		c.remove(c._bufNodePtr, EvictExpired)
+		return nil, false
+	}
+
 	if c._bufNodePtr != nil {
 		// Hits a key, drop it from the original location, and insert it
 		// to the location between root.prev and root (The latest location in cache)
 		atomic.AddInt64(&c.hits, 1)
 		c._bufNodePtr.prev.next = c._bufNodePtr.next
 		c._bufNodePtr.next.prev = c._bufNodePtr.prev
-		c.root = c.root.next
+		// Only the root node needs to give up its location, other nodes
+		// must keep the root as the oldest one in cache.
+		if c._bufNodePtr == c.root {
+			c.root = c.root.next
+		}
 		c._bufNodePtr.prev = c.root.prev
 		c._bufNodePtr.next = c.root
 
 		c.root.prev.next = c._bufNodePtr
 		c.root.prev = c._bufNodePtr
 
+		if c.policy != nil {
+			c.policy.OnAccess(c._bufNodePtr)
+		}
 		return c._bufNodePtr.value, true
 	}
 
@@ -144,36 +514,353 @@ func (c *lruCache) get(k string) (interface{}, bool) {
 // don't pass binary data as string or byte slice, it can increase the risk of
 // data conflict. Keep string or byte slice as printable is a good idea to avoid
 // potential data conflict.
-func (c *lruCache) MSet(kvs ...interface{}) (isRemove bool) {
+func (c *LRUCache) MSet(kvs ...interface{}) (isRemove bool) {
 	if len(kvs) < 2 {
-		panic("at least one key and one value")
+		panic(ErrTooFewArgs)
 	}
 	c.lock.Lock()
-	key := sbconv.BytesToString(interfaceToBytesWithBuf(c._buf, kvs[:len(kvs)-1]...))
-	// Grow buffer slice to preparing enough space for next conversion.
-	if cap(c._buf) < len(key) {
-		c._buf = make([]byte, 0, len(key))
+	defer c.lock.Unlock()
+	key := c.key(kvs[:len(kvs)-1]...)
+	value := kvs[len(kvs)-1]
+	isRemove = c.set(key, value, 0, c.weigh(key, value))
+	return
+}
+
+// Set multi-keys and corresponding single value with a time-to-live, see
+// MSet and SetWithTTL for the details.
+func (c *LRUCache) MSetWithTTL(ttl time.Duration, kvs ...interface{}) (isRemove bool) {
+	if len(kvs) < 2 {
+		panic(ErrTooFewArgs)
 	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key := c.key(kvs[:len(kvs)-1]...)
 	value := kvs[len(kvs)-1]
-	isRemove = c.set(key, value)
-	c.lock.Unlock()
+	isRemove = c.set(key, value, expireAt(ttl), c.weigh(key, value))
+	return
+}
+
+// Set multi-keys and corresponding single value with a cost, see MSet and
+// SetWithCost for the details.
+func (c *LRUCache) MSetWithCost(cost int64, kvs ...interface{}) (isRemove bool) {
+	if len(kvs) < 2 {
+		panic(ErrTooFewArgs)
+	}
+	if cost < 0 {
+		panic(ErrInvalidCost)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key := c.key(kvs[:len(kvs)-1]...)
+	isRemove = c.set(key, kvs[len(kvs)-1], 0, cost)
 	return
 }
 
 // Get value via multi-keys.
-func (c *lruCache) MGet(keys ...interface{}) (value interface{}, ok bool) {
+func (c *LRUCache) MGet(keys ...interface{}) (value interface{}, ok bool) {
 	c.lock.Lock()
-	key := sbconv.BytesToString(interfaceToBytesWithBuf(c._buf, keys...))
-	// Grow buffer slice to preparing enough space for next conversion.
-	if cap(c._buf) < len(key) {
-		c._buf = make([]byte, 0, len(key))
-	}
+	defer c.lock.Unlock()
+	key := c.key(keys...)
 	value, ok = c.get(key)
-	c.lock.Unlock()
 	return
 }
 
-func (c *lruCache) Len() int {
+// TrySet is like Set, but it returns ErrUnsupportedKey instead of panic if
+// the type of key is not supported.
+func (c *LRUCache) TrySet(key, value interface{}) (isRemove bool, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	k, err := c.tryKey(key)
+	if err == nil {
+		isRemove = c.set(k, value, 0, c.weigh(k, value))
+	}
+	return
+}
+
+// TryGet is like Get, but it returns ErrUnsupportedKey instead of panic if
+// the type of key is not supported.
+func (c *LRUCache) TryGet(key interface{}) (value interface{}, ok bool, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	k, err := c.tryKey(key)
+	if err == nil {
+		value, ok = c.get(k)
+	}
+	return
+}
+
+// TryMSet is like MSet, but it returns an error instead of panic if the
+// arguments are invalid.
+func (c *LRUCache) TryMSet(kvs ...interface{}) (isRemove bool, err error) {
+	if len(kvs) < 2 {
+		return false, ErrTooFewArgs
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key, err := c.tryKey(kvs[:len(kvs)-1]...)
+	if err == nil {
+		isRemove = c.set(key, kvs[len(kvs)-1], 0, c.weigh(key, kvs[len(kvs)-1]))
+	}
+	return
+}
+
+// TryMGet is like MGet, but it returns ErrUnsupportedKey instead of panic if
+// the type of any key is not supported.
+func (c *LRUCache) TryMGet(keys ...interface{}) (value interface{}, ok bool, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key, err := c.tryKey(keys...)
+	if err == nil {
+		value, ok = c.get(key)
+	}
+	return
+}
+
+// Peek value via a single key without updating the LRU order and the
+// statistics of cache.
+func (c *LRUCache) Peek(key interface{}) (value interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	k := c.key(key)
+	if n := c.peek(k); n != nil {
+		value, ok = n.value, true
+	}
+	return
+}
+
+// Peek value via multi-keys, see Peek.
+func (c *LRUCache) MPeek(keys ...interface{}) (value interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key := c.key(keys...)
+	if n := c.peek(key); n != nil {
+		value, ok = n.value, true
+	}
+	return
+}
+
+// Contains reports whether the key is in cache, it doesn't update the LRU
+// order and the statistics of cache.
+func (c *LRUCache) Contains(key interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	k := c.key(key)
+	ok := c.peek(k) != nil
+	return ok
+}
+
+// MContains reports whether the multi-keys are in cache, see Contains.
+func (c *LRUCache) MContains(keys ...interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key := c.key(keys...)
+	ok := c.peek(key) != nil
+	return ok
+}
+
+// Returns the node of a single string, nil if it is not in cache or it is
+// expired. The expired node is kept, it will be removed by get or janitor.
+func (c *LRUCache) peek(k string) *Entry {
+	n := c.m[k]
+	if n == nil || n.expire != 0 && n.expire <= time.Now().UnixNano() {
+		return nil
+	}
+	return n
+}
+
+// Delete value via a single key.
+//
+// The returned value indicates whether the key is in cache.
+func (c *LRUCache) Delete(key interface{}) (isDelete bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	k := c.key(key)
+	isDelete = c.delete(k)
+	return
+}
+
+// Delete value via multi-keys.
+//
+// The returned value indicates whether the keys are in cache.
+func (c *LRUCache) MDelete(keys ...interface{}) (isDelete bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key := c.key(keys...)
+	isDelete = c.delete(key)
+	return
+}
+
+// Delete value via a single string.
+//
+// Do not store the input string in any situations since it is just
+// a pseudo-string, which is actually a byte slice in shared buffer.
+func (c *LRUCache) delete(k string) bool {
+	c._bufNodePtr = c.m[k]
+	if c._bufNodePtr == nil {
+		return false
+	}
+	c.remove(c._bufNodePtr, EvictDeleted)
+	return true
+}
+
+// Remove a node from both the map and the linked list.
+//
+// The list always holds maxSize nodes once the cache is full, and the root
+// is the oldest one which will be reused by the next set. So if the cache is
+// full, we turn the node into an empty one and make it the new root, then the
+// next set reuses it instead of eliminating an alive key. Otherwise the root
+// is already an empty node, we just drop the node from the list.
+func (c *LRUCache) remove(n *Entry, reason EvictReason) {
+	if n == c.cursor {
+		c.cursor = n.next
+	}
+	c.evict(n.key, n.value, reason)
+	if c.policy != nil {
+		c.policy.OnRemove(n)
+	}
+	delete(c.m, n.key)
+	c.cost -= n.cost
+	c.bytes -= n.size
+	n.key = ""
+	n.value = nil
+	n.expire = 0
+	n.cost = 0
+	n.size = 0
+
+	if len(c.m) == c.maxSize-1 {
+		// The cache was full.
+		if n != c.root {
+			n.prev.next = n.next
+			n.next.prev = n.prev
+
+			n.prev = c.root.prev
+			n.next = c.root
+			c.root.prev.next = n
+			c.root.prev = n
+			c.root = n
+		}
+		return
+	}
+
+	n.prev.next = n.next
+	n.next.prev = n.prev
+}
+
+// Purge removes all keys from cache, the statistics of cache are kept.
+//
+// The eviction callback is called for every key with EvictDeleted.
+func (c *LRUCache) Purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.onEvict != nil {
+		n := c.root
+		for {
+			if n.key != "" {
+				c.evict(n.key, n.value, EvictDeleted)
+			}
+			if n = n.next; n == c.root {
+				break
+			}
+		}
+	} else {
+		atomic.AddInt64(&c.deletes, int64(len(c.m)))
+	}
+	root := &Entry{}
+	root.next = root
+	root.prev = root
+	c.root = root
+	c.m = make(map[string]*Entry, c.mapSizeHint())
+	c.cost = 0
+	c.bytes = 0
+	if c.policy != nil {
+		c.policy = c.newPolicy()
+		if c.maxCost == 0 {
+			c.setPolicyCapacity(c.maxSize)
+		}
+	}
+	if c.cursor != nil {
+		// A sweep is running, let it walk the new list.
+		c.cursor = root
+	}
+}
+
+// Resize changes the max size of cache, the oldest keys are eliminated if
+// the cache is shrunk, and the eviction callback is called for them with
+// EvictCapacity. The max total cost is changed instead if the cache is
+// created by NewWeighted.
+//
+// The returned value is the number of eliminated keys.
+func (c *LRUCache) Resize(maxSize int) (evicted int) {
+	if maxSize <= 0 {
+		panic(ErrInvalidSize)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.maxCost > 0 {
+		c.maxCost = int64(maxSize)
+		for c.cost > c.maxCost {
+			c.evictVictim()
+			evicted++
+		}
+		// The number of keys which fit is unknown until the cache is full.
+		n := 0
+		if evicted > 0 {
+			n = len(c.m)
+		}
+		c.setPolicyCapacity(n)
+	} else if maxSize > c.maxSize {
+		c.setPolicyCapacity(maxSize)
+		if len(c.m) == c.maxSize {
+			// The cache is full, insert an empty node as the new root,
+			// then the next set will use it instead of the oldest key.
+			n := &Entry{}
+			n.prev = c.root.prev
+			n.next = c.root
+			c.root.prev.next = n
+			c.root.prev = n
+			c.root = n
+		}
+	} else {
+		c.setPolicyCapacity(maxSize)
+		for len(c.m) > maxSize {
+			c.evictVictim()
+			evicted++
+		}
+		if len(c.m) == maxSize && c.root.key == "" {
+			// The cache becomes full, drop the empty root.
+			n := c.root
+			n.prev.next = n.next
+			n.next.prev = n.prev
+			c.root = n.next
+			if n == c.cursor {
+				c.cursor = n.next
+			}
+		}
+	}
+	if c.maxCost == 0 {
+		c.maxSize = maxSize
+	}
+	return
+}
+
+// Count the eviction and call the eviction callback if it is configured.
+func (c *LRUCache) evict(k string, value interface{}, reason EvictReason) {
+	switch reason {
+	case EvictCapacity:
+		atomic.AddInt64(&c.evictions, 1)
+	case EvictDeleted:
+		atomic.AddInt64(&c.deletes, 1)
+	case EvictExpired:
+		atomic.AddInt64(&c.expirations, 1)
+	}
+	if c.onEvict != nil {
+		c.onEvict(decodeKey(k, c.encoding), value, reason)
+	}
+}
+
+// Len returns the number of keys in cache, including the expired keys
+// which have not been removed yet.
+func (c *LRUCache) Len() int {
 	c.lock.Lock()
 	l := len(c.m)
 	c.lock.Unlock()
@@ -181,15 +868,54 @@ func (c *lruCache) Len() int {
 	return l
 }
 
-func (c *lruCache) HitRatio() float64 {
+// Cap returns the max size of cache, or the max total cost if the cache is
+// created by NewWeighted.
+func (c *LRUCache) Cap() int {
+	c.lock.Lock()
+	n := c.maxSize
+	if c.maxCost > 0 {
+		n = int(c.maxCost)
+	}
+	c.lock.Unlock()
+	return n
+}
+
+// Cost returns the total cost of keys in cache, including the expired keys
+// which have not been removed yet.
+func (c *LRUCache) Cost() int64 {
+	c.lock.Lock()
+	cost := c.cost
+	c.lock.Unlock()
+	return cost
+}
+
+// Bytes returns the estimated memory size of keys and values in cache,
+// including the expired keys which have not been removed yet, see Sizer.
+func (c *LRUCache) Bytes() int64 {
+	c.lock.Lock()
+	bytes := c.bytes
+	c.lock.Unlock()
+	return bytes
+}
+
+// HitRatio returns hits / (hits + misses), lookups of expired keys are
+// seen as misses. It returns 0 if there is no lookup.
+func (c *LRUCache) HitRatio() float64 {
 	hits := atomic.LoadInt64(&c.hits)
-	misses := atomic.LoadInt64(&c.misses)
+	misses := atomic.LoadInt64(&c.misses) + atomic.LoadInt64(&c.expired)
 
-	return float64(hits) / float64(misses+hits)
+	return hitRatio(hits, misses)
 }
 
-func (c *lruCache) Info() (hits, misses int64) {
+// Info returns the number of hits and misses, lookups of expired keys
+// are not included in misses, see Expired.
+func (c *LRUCache) Info() (hits, misses int64) {
 	hits = atomic.LoadInt64(&c.hits)
 	misses = atomic.LoadInt64(&c.misses)
 	return
 }
+
+// Expired returns the number of lookups which found an expired key.
+func (c *LRUCache) Expired() int64 {
+	return atomic.LoadInt64(&c.expired)
+}
@@ -201,3 +201,553 @@ func TestLruCache_Others(t *testing.T) {
 	}
 
 }
+
+func TestLRUCache_Delete(t *testing.T) {
+	l := New(3)
+
+	// Delete non-existent key
+	if l.Delete(1) {
+		t.Error("delete non-existent key error")
+	}
+
+	// Delete while cache is not full
+	l.Set(1, 1)
+	l.Set(2, 2)
+	if !l.Delete(1) || l.Len() != 1 {
+		t.Error("delete error")
+	}
+	if v, ok := l.Get(1); v != nil || ok {
+		t.Error("get deleted key error")
+	}
+
+	// Delete the root while cache is full
+	l.Set(3, 3)
+	l.Set(4, 4) // Now is 2(root), 3, 4
+	if l.root.value != 2 {
+		t.Error("linked list error")
+	}
+	if !l.Delete(2) || l.Len() != 2 {
+		t.Error("delete root error")
+	}
+	if l.Set(5, 5) { // Reuse the empty root, now is 3(root), 4, 5
+		t.Error("eliminate key after delete error")
+	}
+	if l.root.value != 3 || l.root.next.value != 4 || l.root.next.next.value != 5 {
+		t.Error("linked list error")
+	}
+
+	// Delete a non-root node while cache is full
+	if !l.Delete(4) || l.Len() != 2 {
+		t.Error("delete error")
+	}
+	if l.Set(6, 6) { // Reuse the empty root, now is 3(root), 5, 6
+		t.Error("eliminate key after delete error")
+	}
+	if l.root.value != 3 || l.root.next.value != 5 || l.root.next.next.value != 6 {
+		t.Error("linked list error")
+	}
+	if !l.Set(7, 7) { // Now is 5(root), 6, 7
+		t.Error("eliminate key error")
+	}
+	if v, ok := l.Get(3); v != nil || ok {
+		t.Error("get eliminated key error")
+	}
+
+	// Delete all keys
+	for _, k := range []int{5, 6, 7} {
+		if !l.Delete(k) {
+			t.Error("delete error")
+		}
+	}
+	if l.Len() != 0 {
+		t.Error("length error")
+	}
+
+	// Multi-keys
+	l.MSet(1, "2", 3)
+	if l.MDelete(1, 2) || !l.MDelete(1, "2") {
+		t.Error("MDelete error")
+	}
+	if v, ok := l.MGet(1, "2"); v != nil || ok {
+		t.Error("MGet deleted key error")
+	}
+
+	// maxSize=1
+	l = New(1)
+	l.Set(1, 1)
+	if !l.Delete(1) || l.Len() != 0 {
+		t.Error("maxSize=1 delete error")
+	}
+	l.Set(2, 2)
+	if v, ok := l.Get(2); v != 2 || !ok || l.root.next != l.root {
+		t.Error("maxSize=1 delete error")
+	}
+}
+
+func TestLRUCache_GetOrder(t *testing.T) {
+	l := New(3)
+	l.Set(1, 1)
+	l.Set(2, 2)
+	l.Get(1) // Not full, now is 2, 1
+	l.Set(3, 3)
+	if !l.Set(4, 4) { // Now is 1(root), 3, 4
+		t.Error("eliminate key error")
+	}
+	if _, ok := l.Get(2); ok {
+		t.Error("get order error")
+	}
+
+	l.Get(3) // Now is 1(root), 4, 3
+	l.Set(5, 5)
+	if _, ok := l.Get(1); ok {
+		t.Error("get order error")
+	}
+	if l.root.value != 4 || l.root.next.value != 3 || l.root.next.next.value != 5 {
+		t.Error("linked list error")
+	}
+}
+
+func TestLRUCache_Interface(t *testing.T) {
+	var c Cache = New(8)
+	c.MSet(1, 2, "value")
+	if v, ok := c.MGet(1, 2); v != "value" || !ok {
+		t.Error("interface error")
+	}
+	if _, ok := c.(*LRUCache); !ok {
+		t.Error("concrete type error")
+	}
+}
+
+func TestLRUCache_TTL(t *testing.T) {
+	l := New(3)
+	l.SetWithTTL(1, 1, time.Millisecond)
+	l.MSetWithTTL(time.Hour, 1, 2, 2)
+	l.SetWithTTL(3, 3, 0)
+	if v, ok := l.Get(1); v != 1 || !ok {
+		t.Error("get unexpired key error")
+	}
+
+	time.Sleep(time.Millisecond * 5)
+	if v, ok := l.Get(1); v != nil || ok {
+		t.Error("get expired key error")
+	}
+	if l.Len() != 2 {
+		t.Error("remove expired key error")
+	}
+	if v, ok := l.MGet(1, 2); v != 2 || !ok {
+		t.Error("get unexpired key error")
+	}
+	if v, ok := l.Get(3); v != 3 || !ok {
+		t.Error("get never expired key error")
+	}
+
+	// Set without ttl clears the expiration time
+	l.SetWithTTL(4, 4, time.Millisecond)
+	l.Set(4, 4)
+	time.Sleep(time.Millisecond * 5)
+	if v, ok := l.Get(4); v != 4 || !ok {
+		t.Error("clear expiration time error")
+	}
+
+	hits, misses := l.Info()
+	if hits != 4 || misses != 0 || l.Expired() != 1 || l.HitRatio() != float64(0.8) {
+		t.Error("expired info error")
+	}
+}
+
+func TestLRUCache_OnEvict(t *testing.T) {
+	type evicted struct {
+		key    string
+		value  interface{}
+		reason EvictReason
+	}
+	var events []evicted
+	l := New(2, OnEvict(func(key []interface{}, value interface{}, reason EvictReason) {
+		events = append(events, evicted{fmt.Sprint(key...), value, reason})
+	}))
+
+	l.Set(1, 1)
+	l.MSet("a", 2, 2)
+	l.Set(1, 3)
+	l.Set(3, 3)
+	l.Delete(3)
+	l.SetWithTTL(4, 4, time.Millisecond)
+	time.Sleep(time.Millisecond * 5)
+	l.Get(4)
+
+	expected := []evicted{
+		{"1", 1, EvictOverwritten},
+		{"1", 3, EvictCapacity},
+		{"3", 3, EvictDeleted},
+		{"4", 4, EvictExpired},
+	}
+	if len(events) != len(expected) {
+		t.Fatal("eviction callback error", events)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Error("eviction callback error", events[i], expected[i])
+		}
+	}
+
+	if EvictCapacity.String() != "capacity" || EvictReason(255).String() != "unknown" {
+		t.Error("eviction reason string error")
+	}
+}
+
+func TestLRUCache_Peek_Contains(t *testing.T) {
+	l := New(3)
+	l.Set(1, 1)
+	l.Set(2, 2)
+	l.MSet(3, "3", 3)
+
+	// Now is 1(root), 2, (3, "3"), Peek must not update the order
+	if v, ok := l.Peek(1); v != 1 || !ok {
+		t.Error("Peek exists key error")
+	}
+	if v, ok := l.MPeek(3, "3"); v != 3 || !ok {
+		t.Error("MPeek exists key error")
+	}
+	if !l.Contains(1) || !l.MContains(3, "3") || l.Contains(4) || l.MContains(3, 3) {
+		t.Error("Contains error")
+	}
+	if v, ok := l.Peek(4); v != nil || ok {
+		t.Error("Peek non-existent key error")
+	}
+	if l.root.value != 1 {
+		t.Error("Peek updates order error")
+	}
+	if hits, misses := l.Info(); hits != 0 || misses != 0 {
+		t.Error("Peek updates info error")
+	}
+
+	l.Set(4, 4)
+	if l.Contains(1) {
+		t.Error("Peek updates order error")
+	}
+
+	// Expired keys
+	l.SetWithTTL(5, 5, time.Millisecond)
+	time.Sleep(time.Millisecond * 5)
+	if _, ok := l.Peek(5); ok || l.Contains(5) || l.Expired() != 0 {
+		t.Error("Peek expired key error")
+	}
+}
+
+func TestLRUCache_Purge(t *testing.T) {
+	var evicted []interface{}
+	l := New(3, OnEvict(func(key []interface{}, value interface{}, reason EvictReason) {
+		if reason == EvictDeleted {
+			evicted = append(evicted, value)
+		}
+	}))
+	l.Set(1, 1)
+	l.Set(2, 2)
+	l.Get(1)
+	l.Purge()
+	if l.Len() != 0 || len(evicted) != 2 || evicted[0] != 2 || evicted[1] != 1 {
+		t.Error("purge error")
+	}
+	if _, ok := l.Get(1); ok {
+		t.Error("get purged key error")
+	}
+	if hits, misses := l.Info(); hits != 1 || misses != 1 {
+		t.Error("purge should keep info")
+	}
+
+	for i := 0; i < 4; i++ {
+		l.Set(i, i)
+	}
+	l.Purge()
+	if l.Len() != 0 || len(evicted) != 5 {
+		t.Error("purge full cache error")
+	}
+	for i := 0; i < 3; i++ {
+		if l.Set(i, i) {
+			t.Error("set after purge error")
+		}
+	}
+}
+
+func TestLRUCache_Resize(t *testing.T) {
+	var evicted []interface{}
+	l := New(3, OnEvict(func(key []interface{}, value interface{}, reason EvictReason) {
+		if reason == EvictCapacity {
+			evicted = append(evicted, value)
+		}
+	}))
+
+	// Grow a full cache
+	l.Set(1, 1)
+	l.Set(2, 2)
+	l.Set(3, 3)
+	if l.Resize(5) != 0 {
+		t.Error("grow error")
+	}
+	if l.Set(4, 4) || l.Set(5, 5) || !l.Set(6, 6) {
+		t.Error("grow error")
+	}
+	if l.Contains(1) || !l.Contains(2) {
+		t.Error("grow order error")
+	}
+
+	// Shrink a full cache, now is 2(root), 3, 4, 5, 6
+	if l.Resize(3) != 2 || l.Len() != 3 {
+		t.Error("shrink error")
+	}
+	if len(evicted) != 3 || evicted[1] != 2 || evicted[2] != 3 {
+		t.Error("shrink order error", evicted)
+	}
+	if l.root.value != 4 || l.root.next.value != 5 || l.root.next.next.value != 6 || l.root.prev.value != 6 {
+		t.Error("linked list error")
+	}
+	if !l.Set(7, 7) || l.Contains(4) {
+		t.Error("set after shrink error")
+	}
+
+	// Shrink a cache which is not full
+	l.Delete(5)
+	if l.Resize(4) != 0 || l.Set(8, 8) || l.Set(9, 9) || !l.Set(10, 10) {
+		t.Error("shrink error")
+	}
+	l.Delete(10)
+	l.Delete(9)
+	if l.Resize(2) != 0 || l.Len() != 2 || !l.Set(11, 11) || l.Contains(6) {
+		t.Error("shrink error")
+	}
+
+	// Shrink to 1
+	if l.Resize(1) != 1 || l.Len() != 1 || l.root.next != l.root || !l.Contains(11) {
+		t.Error("shrink error")
+	}
+}
+
+func TestLRUCache_Try(t *testing.T) {
+	if l, err := NewWithOptions(0); l != nil || err != ErrInvalidSize {
+		t.Error("NewWithOptions error")
+	}
+	l, err := NewWithOptions(8)
+	if l == nil || err != nil {
+		t.Fatal("NewWithOptions error")
+	}
+
+	if isRemove, err := l.TrySet(1, 1); isRemove || err != nil {
+		t.Error("TrySet error")
+	}
+	if v, ok, err := l.TryGet(1); v != 1 || !ok || err != nil {
+		t.Error("TryGet error")
+	}
+	if isRemove, err := l.TryMSet(1, 2, 3); isRemove || err != nil {
+		t.Error("TryMSet error")
+	}
+	if v, ok, err := l.TryMGet(1, 2); v != 3 || !ok || err != nil {
+		t.Error("TryMGet error")
+	}
+
+	// Bad keys and arguments
+	if _, err := l.TrySet([]int{}, 1); err != ErrUnsupportedKey {
+		t.Error("TrySet unsupported key error")
+	}
+	if _, _, err := l.TryGet(nil); err != ErrUnsupportedKey {
+		t.Error("TryGet unsupported key error")
+	}
+	if _, err := l.TryMSet(1); err != ErrTooFewArgs {
+		t.Error("TryMSet too few args error")
+	}
+	if _, err := l.TryMSet(1, []int{}, 1); err != ErrUnsupportedKey {
+		t.Error("TryMSet unsupported key error")
+	}
+	if _, _, err := l.TryMGet(1, []int{}); err != ErrUnsupportedKey {
+		t.Error("TryMGet unsupported key error")
+	}
+	if l.Len() != 2 {
+		t.Error("set bad key error")
+	}
+}
+
+func TestLRUCache_PanicUnlock(t *testing.T) {
+	l := New(8)
+	for _, fn := range []func(){
+		func() { l.Set([]int{}, 1) },
+		func() { l.Get([]int{}) },
+		func() { l.MSet(1, []int{}, 1) },
+		func() { l.MSet(1) },
+		func() { l.Delete([]int{}) },
+		func() { l.Contains([]int{}) },
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r != ErrUnsupportedKey && r != ErrTooFewArgs {
+					t.Error("panic error", r)
+				}
+			}()
+			fn()
+		}()
+		// The lock must be released
+		l.Set(1, 1)
+	}
+
+	func() {
+		defer func() {
+			if recover() != ErrInvalidSize {
+				t.Error("New panic error")
+			}
+		}()
+		New(0)
+	}()
+}
+
+type panicSizer struct{}
+
+func (panicSizer) Size() int64 { panic("size") }
+
+func TestLRUCache_CallbackPanicUnlock(t *testing.T) {
+	evict := OnEvict(func(key []interface{}, value interface{}, reason EvictReason) {
+		if value == "evict" {
+			panic("evict")
+		}
+	})
+	weigher := WithWeigher(func(key []interface{}, value interface{}) int64 {
+		if value == "weigh" {
+			panic("weigh")
+		}
+		return 1
+	})
+	l := New(8, evict, weigher)
+	l.Set(2, "evict")
+	s := NewSharded(2, 8, evict, weigher)
+	for _, fn := range []func(){
+		func() { l.Set(3, "weigh") },
+		func() { l.Set(3, panicSizer{}) },
+		func() { l.Delete(2) },
+		func() { l.Range(func(key []interface{}, value interface{}) bool { panic("range") }) },
+		func() {
+			l.GetOrLoad(3, func() (interface{}, error) {
+				return "weigh", nil
+			})
+		},
+		func() { s.Set(1, "weigh") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("callback panic error")
+				}
+			}()
+			fn()
+		}()
+		// The lock must be released
+		l.Set(1, 1)
+		s.Set(1, 1)
+	}
+	if v, err := l.GetOrLoad(3, func() (interface{}, error) { return 3, nil }); v != 3 || err != nil {
+		t.Error("GetOrLoad after panic error")
+	}
+}
+
+func TestLRUCache_Weighted(t *testing.T) {
+	var evicted []interface{}
+	l := NewWeighted(10, OnEvict(func(key []interface{}, value interface{}, reason EvictReason) {
+		if reason == EvictCapacity {
+			evicted = append(evicted, value)
+		}
+	}))
+	if l.SetWithCost(1, 1, 4) || l.SetWithCost(2, 2, 4) || l.Set(3, 3) {
+		t.Error("set with cost error")
+	}
+	if l.Cost() != 9 || l.Len() != 3 {
+		t.Error("cost error")
+	}
+
+	// Eliminate the oldest keys until the cost fits
+	l.Get(1)
+	if !l.MSetWithCost(5, 4, 5, 4) || l.Cost() != 10 || l.Len() != 3 {
+		t.Error("eliminate error", l.Cost())
+	}
+	if len(evicted) != 1 || evicted[0] != 2 || !l.Contains(1) || !l.MContains(4, 5) {
+		t.Error("eliminate order error", evicted)
+	}
+
+	// Update the cost of a key
+	if !l.MSetWithCost(9, 4, 5, 4) || l.Cost() != 9 || l.Len() != 1 || !l.MContains(4, 5) {
+		t.Error("update cost error", l.Cost())
+	}
+	if l.MSetWithCost(2, 4, 5, 4) || l.Cost() != 2 {
+		t.Error("update cost error")
+	}
+
+	// A key which is too large is eliminated alone, the old value of it is
+	// dropped too
+	evicted = nil
+	if !l.SetWithCost(6, 6, 11) || l.Len() != 1 || l.Cost() != 2 || l.Contains(6) || len(evicted) != 1 || evicted[0] != 6 {
+		t.Error("large key error")
+	}
+	if !l.MSetWithCost(11, 4, 5, 7) || l.Len() != 0 || l.Cost() != 0 || len(evicted) != 2 || evicted[1] != 7 {
+		t.Error("update large key error")
+	}
+
+	// Delete, resize and purge
+	l.SetWithCost(1, 1, 3)
+	l.SetWithCost(2, 2, 3)
+	l.SetWithCost(3, 3, 3)
+	l.Delete(2)
+	if l.Cost() != 6 {
+		t.Error("delete cost error")
+	}
+	if l.Resize(5) != 1 || l.Cost() != 3 || !l.Contains(3) {
+		t.Error("resize error")
+	}
+	l.Purge()
+	if l.Cost() != 0 || l.Set(1, 1) || l.Cost() != 1 {
+		t.Error("purge cost error")
+	}
+	l.SetWithTTL(2, 2, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, ok := l.Get(2); ok || l.Cost() != 1 {
+		t.Error("expired cost error")
+	}
+
+	func() {
+		defer func() {
+			if recover() != ErrInvalidCost {
+				t.Error("NewWeighted panic error")
+			}
+		}()
+		NewWeighted(0)
+	}()
+	func() {
+		defer func() {
+			if recover() != ErrInvalidCost {
+				t.Error("SetWithCost panic error")
+			}
+		}()
+		l.SetWithCost(1, 1, -1)
+	}()
+}
+
+func TestLRUCache_Weigher(t *testing.T) {
+	l := NewWeighted(10, WithWeigher(func(key []interface{}, value interface{}) int64 {
+		return int64(len(value.(string)))
+	}))
+	l.Set(1, "1234")
+	l.MSet(1, 2, "1234")
+	if l.Cost() != 8 {
+		t.Error("weigher error")
+	}
+	if !l.Set(2, "123") || l.Contains(1) || l.Cost() != 7 {
+		t.Error("weigher eliminate error")
+	}
+	// The cost passed explicitly is used instead
+	l.SetWithCost(3, "123456", 1)
+	if l.Cost() != 8 {
+		t.Error("weigher with cost error")
+	}
+
+	// The cost of a key is also tracked by a cache created by New
+	c := New(2, WithWeigher(func(key []interface{}, value interface{}) int64 {
+		return 100
+	}))
+	if c.Set(1, 1) || c.Set(2, 2) || c.Cost() != 200 || !c.Set(3, 3) || c.Cost() != 200 {
+		t.Error("weigher of New error")
+	}
+}
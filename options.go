@@ -0,0 +1,86 @@
+package lrucache
+
+import "time"
+
+// Option configures a cache created by New.
+type Option func(o *options)
+
+type options struct {
+	janitorInterval time.Duration
+	onEvict         func(key []interface{}, value interface{}, reason EvictReason)
+	keyEncoding     KeyEncoding
+	weigher         func(key []interface{}, value interface{}) int64
+	maxBytes        int64
+	newPolicy       func() Policy
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithJanitor starts a background goroutine which removes expired keys
+// from cache every interval, use Close to stop it.
+//
+// The cache will never be garbage collected before Close is called, since
+// the goroutine holds a reference to it.
+func WithJanitor(interval time.Duration) Option {
+	return func(o *options) {
+		o.janitorInterval = interval
+	}
+}
+
+// OnEvict sets a callback which is called when a key leaves the cache or its
+// value is overwritten, key is the arguments used to set the value.
+//
+// The callback is called while holding the lock of cache, so it must not use
+// the cache, otherwise a deadlock will occur.
+func OnEvict(fn func(key []interface{}, value interface{}, reason EvictReason)) Option {
+	return func(o *options) {
+		o.onEvict = fn
+	}
+}
+
+// WithKeyEncoding sets the encoding used to convert keys, NativeEncoding is
+// used by default. Use CanonicalEncoding if the keys will be shared between
+// different platforms.
+func WithKeyEncoding(encoding KeyEncoding) Option {
+	return func(o *options) {
+		o.keyEncoding = encoding
+	}
+}
+
+// WithWeigher sets a function which returns the cost of a key and its value,
+// it is used by the methods which set keys without a cost, see NewWeighted.
+// A negative cost is seen as 0.
+//
+// The function is called while holding the lock of cache, so it must not use
+// the cache, otherwise a deadlock will occur.
+func WithWeigher(fn func(key []interface{}, value interface{}) int64) Option {
+	return func(o *options) {
+		o.weigher = fn
+	}
+}
+
+// WithMaxBytes limits the estimated memory size of keys and values in cache,
+// the oldest keys are eliminated until the size fits after setting a key,
+// but a key larger than maxBytes is eliminated at once and the other keys
+// are kept. See Sizer for how the size is estimated. It works along with the
+// max size or the max cost of cache.
+func WithMaxBytes(maxBytes int64) Option {
+	return func(o *options) {
+		o.maxBytes = maxBytes
+	}
+}
+
+// WithPolicy sets the eviction policy of cache, newPolicy is called to
+// create a policy for every cache (or every shard of ShardedCache), and
+// it is called again by Purge. LRU is used by default.
+func WithPolicy(newPolicy func() Policy) Option {
+	return func(o *options) {
+		o.newPolicy = newPolicy
+	}
+}
@@ -0,0 +1,105 @@
+package lrucache
+
+// Policy decides which key is eliminated when the cache is full, use
+// WithPolicy to set it.
+//
+// The cache always keeps its keys in a linked list ordered by recency, which
+// is used by Range, Oldest, Newest and the janitor, so the policy only needs
+// to track the information required by its own algorithm.
+//
+// The methods are called while holding the lock of cache, so they must not
+// use the cache, otherwise a deadlock will occur. The entries are reused by
+// cache after they are removed, so the policy must drop its references to
+// an entry in OnRemove.
+type Policy interface {
+	// OnInsert is called after a new key is added to cache.
+	OnInsert(e *Entry)
+	// OnAccess is called after a key is hit by Get, MGet, GetOrLoad and
+	// their variants.
+	OnAccess(e *Entry)
+	// OnRemove is called before a key leaves cache for any reason.
+	OnRemove(e *Entry)
+	// Victim returns the key to be eliminated, nil means the least recently
+	// used one.
+	Victim() *Entry
+}
+
+// LRU returns the default policy, which eliminates the least recently used
+// key.
+func LRU() Policy {
+	return lruPolicy{}
+}
+
+// The recency list is maintained by cache, so nothing needs to be tracked.
+type lruPolicy struct{}
+
+func (lruPolicy) OnInsert(e *Entry) {}
+
+func (lruPolicy) OnAccess(e *Entry) {}
+
+func (lruPolicy) OnRemove(e *Entry) {}
+
+func (lruPolicy) Victim() *Entry { return nil }
+
+// Implemented by policies which need the number of keys the cache can hold,
+// setCapacity is called with the max size of cache when the policy is
+// created and when the cache is resized. The cache bounded by cost or bytes
+// has no fixed number, it calls setCapacity with the number of keys which
+// fit after eliminating keys, and 0 means the number is unknown yet.
+type capacityPolicy interface {
+	setCapacity(n int)
+}
+
+// Implemented by policies which need the key being inserted before the victim
+// is chosen for it, e.g. ARC adapts to the key remembered in its ghost lists.
+// The key is converted, see Entry.Key.
+type insertPolicy interface {
+	beforeInsert(key string)
+}
+
+// Tell the policy the number of keys the cache can hold, see capacityPolicy.
+func (c *LRUCache) setPolicyCapacity(n int) {
+	if p, ok := c.policy.(capacityPolicy); ok {
+		p.setCapacity(n)
+	}
+}
+
+// A doubly linked list of entries used by policies, the entries are linked
+// via their own fields, so an entry can only be in one list at a time.
+type entryList struct {
+	// The head is the oldest entry, and the tail is the newest one.
+	head, tail *Entry
+	len        int
+}
+
+// Append e to the tail of list.
+func (l *entryList) pushBack(e *Entry) {
+	e.list = l
+	e.lprev = l.tail
+	e.lnext = nil
+	if l.tail != nil {
+		l.tail.lnext = e
+	} else {
+		l.head = e
+	}
+	l.tail = e
+	l.len++
+}
+
+// Remove e from list, e must be in it.
+func (l *entryList) remove(e *Entry) {
+	if e.lprev != nil {
+		e.lprev.lnext = e.lnext
+	} else {
+		l.head = e.lnext
+	}
+	if e.lnext != nil {
+		e.lnext.lprev = e.lprev
+	} else {
+		l.tail = e.lprev
+	}
+	e.list = nil
+	e.lprev = nil
+	e.lnext = nil
+	l.len--
+}
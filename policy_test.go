@@ -0,0 +1,109 @@
+package lrucache
+
+import "testing"
+
+// Eliminates the earliest inserted key, accesses are ignored.
+type fifoPolicy struct {
+	entries  []*Entry
+	accesses int
+}
+
+func (p *fifoPolicy) OnInsert(e *Entry) { p.entries = append(p.entries, e) }
+
+func (p *fifoPolicy) OnAccess(e *Entry) { p.accesses++ }
+
+func (p *fifoPolicy) OnRemove(e *Entry) {
+	for i := range p.entries {
+		if p.entries[i] == e {
+			p.entries = append(p.entries[:i], p.entries[i+1:]...)
+			return
+		}
+	}
+	panic("remove unknown entry")
+}
+
+func (p *fifoPolicy) Victim() *Entry {
+	if len(p.entries) == 0 {
+		return nil
+	}
+	return p.entries[0]
+}
+
+func TestLRUCache_Policy(t *testing.T) {
+	var policies []*fifoPolicy
+	l := New(3, WithPolicy(func() Policy {
+		p := &fifoPolicy{}
+		policies = append(policies, p)
+		return p
+	}))
+	l.Set(1, 1)
+	l.Set(2, 2)
+	l.Set(3, 3)
+	l.Get(1)
+	l.Get(2)
+	// Key 1 is eliminated though it is recently used
+	if !l.Set(4, 4) || l.Contains(1) || !l.Contains(3) || policies[0].accesses != 2 {
+		t.Error("policy victim error")
+	}
+	if !l.Set(5, 5) || l.Contains(2) || len(policies[0].entries) != 3 {
+		t.Error("policy victim error")
+	}
+
+	// The recency list is kept
+	if key, _, _ := l.Oldest(); key[0] != 3 {
+		t.Error("recency list error", key)
+	}
+
+	// Delete, shrink and purge
+	l.Delete(4)
+	if len(policies[0].entries) != 2 || l.Set(6, 6) {
+		t.Error("policy delete error")
+	}
+	if l.Resize(2) != 1 || l.Contains(3) || len(policies[0].entries) != 2 {
+		t.Error("policy resize error")
+	}
+	l.Purge()
+	if len(policies) != 2 || len(policies[1].entries) != 0 {
+		t.Error("policy purge error")
+	}
+	l.Set(7, 7)
+	l.Set(8, 8)
+	if !l.Set(9, 9) || l.Contains(7) || len(policies[1].entries) != 2 {
+		t.Error("policy after purge error")
+	}
+
+	// Works with the max cost
+	l = NewWeighted(3, WithPolicy(func() Policy { return &fifoPolicy{} }))
+	l.Set(1, 1)
+	l.Set(2, 2)
+	l.Get(1)
+	if !l.SetWithCost(3, 3, 2) || l.Contains(1) || !l.Contains(2) {
+		t.Error("policy with cost error")
+	}
+}
+
+func TestEntry(t *testing.T) {
+	p := &fifoPolicy{}
+	l := NewWeighted(8, WithPolicy(func() Policy { return p }))
+	l.SetWithCost(1, "a", 3)
+	l.Set(2, 2)
+	e := p.entries[0]
+	if e.Key() != string(interfaceToBytes(1)) || e.Value() != "a" || e.Cost() != 3 || e.Size() != entrySize(e.Key(), "a") {
+		t.Error("entry error")
+	}
+	if e = p.entries[1]; e.Key() == p.entries[0].Key() || e.Value() != 2 || e.Cost() != 1 {
+		t.Error("entry error")
+	}
+}
+
+func TestLRUCache_LRUPolicy(t *testing.T) {
+	for _, l := range []*LRUCache{New(3), New(3, WithPolicy(LRU))} {
+		l.Set(1, 1)
+		l.Set(2, 2)
+		l.Set(3, 3)
+		l.Get(1)
+		if !l.Set(4, 4) || l.Contains(2) || !l.Contains(1) {
+			t.Error("LRU policy error")
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Package prom exports the statistics of caches in the Prometheus text
+// exposition format, it only depends on the standard library.
+//
+// Register caches with unique names, then serve the metrics via Handler:
+//
+//	prom.Register("users", l)
+//	http.Handle("/metrics", prom.Handler())
+package prom
+
+import (
+	"bufio"
+	"errors"
+	"github.com/ZYunH/lrucache"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Cache is implemented by the caches of lrucache, e.g. *lrucache.LRUCache
+// and *lrucache.ShardedCache.
+type Cache interface {
+	Stats() lrucache.Stats
+	Cap() int
+}
+
+// ErrDuplicateName is returned if a cache with the same name is registered.
+var ErrDuplicateName = errors.New("duplicate cache name")
+
+// Registry holds named caches and serves their metrics, it is an
+// http.Handler. Use NewRegistry to create one.
+type Registry struct {
+	lock   sync.RWMutex
+	caches map[string]Cache
+}
+
+// DefaultRegistry is the registry used by Register, Unregister and Handler.
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry creates a new empty registry.
+func NewRegistry() *Registry {
+	return &Registry{caches: make(map[string]Cache)}
+}
+
+// Register adds a cache with name, which is used as the value of the
+// "cache" label. ErrDuplicateName is returned if the name is used.
+func (r *Registry) Register(name string, c Cache) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.caches[name]; ok {
+		return ErrDuplicateName
+	}
+	r.caches[name] = c
+	return nil
+}
+
+// Unregister removes the cache of name, the returned value indicates
+// whether it is registered.
+func (r *Registry) Unregister(name string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	_, ok := r.caches[name]
+	delete(r.caches, name)
+	return ok
+}
+
+// Register adds a cache to DefaultRegistry, see Registry.Register.
+func Register(name string, c Cache) error {
+	return DefaultRegistry.Register(name, c)
+}
+
+// Unregister removes a cache from DefaultRegistry, see Registry.Unregister.
+func Unregister(name string) bool {
+	return DefaultRegistry.Unregister(name)
+}
+
+// Handler returns the http.Handler which serves the metrics of
+// DefaultRegistry.
+func Handler() http.Handler {
+	return DefaultRegistry
+}
+
+// The metrics exported for every cache.
+var metrics = []struct {
+	name, typ, help string
+	value           func(s lrucache.Stats, capacity int) float64
+}{
+	{"lrucache_hits_total", "counter", "The number of lookups which found a key.",
+		func(s lrucache.Stats, _ int) float64 { return float64(s.Hits) }},
+	{"lrucache_misses_total", "counter", "The number of lookups which found no key, including the expired keys.",
+		func(s lrucache.Stats, _ int) float64 { return float64(s.Misses + s.Expired) }},
+	{"lrucache_sets_total", "counter", "The number of new keys.",
+		func(s lrucache.Stats, _ int) float64 { return float64(s.Sets) }},
+	{"lrucache_updates_total", "counter", "The number of values replaced by set.",
+		func(s lrucache.Stats, _ int) float64 { return float64(s.Updates) }},
+	{"lrucache_evictions_total", "counter", "The number of keys eliminated since the cache is full.",
+		func(s lrucache.Stats, _ int) float64 { return float64(s.Evictions) }},
+	{"lrucache_deletes_total", "counter", "The number of keys deleted explicitly.",
+		func(s lrucache.Stats, _ int) float64 { return float64(s.Deletes) }},
+	{"lrucache_expirations_total", "counter", "The number of expired keys removed.",
+		func(s lrucache.Stats, _ int) float64 { return float64(s.Expirations) }},
+	{"lrucache_load_successes_total", "counter", "The number of loaders which succeeded.",
+		func(s lrucache.Stats, _ int) float64 { return float64(s.LoadSuccesses) }},
+	{"lrucache_load_failures_total", "counter", "The number of loaders which failed.",
+		func(s lrucache.Stats, _ int) float64 { return float64(s.LoadFailures) }},
+	{"lrucache_load_seconds_total", "counter", "The total time spent in loaders.",
+		func(s lrucache.Stats, _ int) float64 { return s.TotalLoadTime.Seconds() }},
+	{"lrucache_size", "gauge", "The number of keys in cache.",
+		func(s lrucache.Stats, _ int) float64 { return float64(s.Len) }},
+	{"lrucache_capacity", "gauge", "The max size of cache, or the max total cost of a weighted cache.",
+		func(_ lrucache.Stats, capacity int) float64 { return float64(capacity) }},
+	{"lrucache_cost", "gauge", "The total cost of keys in cache.",
+		func(s lrucache.Stats, _ int) float64 { return float64(s.Cost) }},
+	{"lrucache_bytes", "gauge", "The estimated memory size of keys and values in cache.",
+		func(s lrucache.Stats, _ int) float64 { return float64(s.Bytes) }},
+}
+
+// WriteTo writes the metrics of all registered caches to w in the
+// Prometheus text exposition format, the caches are sorted by name.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.lock.RLock()
+	names := make([]string, 0, len(r.caches))
+	for name := range r.caches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	stats := make([]lrucache.Stats, len(names))
+	caps := make([]int, len(names))
+	for i, name := range names {
+		stats[i] = r.caches[name].Stats()
+		caps[i] = r.caches[name].Cap()
+	}
+	r.lock.RUnlock()
+
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, m := range metrics {
+		if len(names) == 0 {
+			break
+		}
+		bw.WriteString("# HELP " + m.name + " " + m.help + "\n")
+		bw.WriteString("# TYPE " + m.name + " " + m.typ + "\n")
+		for i, name := range names {
+			bw.WriteString(m.name + `{cache="` + escapeLabel(name) + `"} `)
+			bw.WriteString(strconv.FormatFloat(m.value(stats[i], caps[i]), 'g', -1, 64))
+			bw.WriteByte('\n')
+		}
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// ServeHTTP serves the metrics of all registered caches.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
+
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Escape the label value as required by the text exposition format.
+func escapeLabel(v string) string {
+	return labelReplacer.Replace(v)
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
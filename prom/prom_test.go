@@ -0,0 +1,77 @@
+package prom
+
+import (
+	"github.com/ZYunH/lrucache"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	l := lrucache.New(2)
+	if r.Register("users", l) != nil || r.Register("users", l) != ErrDuplicateName {
+		t.Error("register error")
+	}
+	s := lrucache.NewSharded(2, 8)
+	if r.Register(`a"b\`, s) != nil {
+		t.Error("register error")
+	}
+
+	l.Set(1, 1)
+	l.Set(2, 2)
+	l.Set(3, 3)
+	l.Get(3)
+	l.Get(1)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Error("content type error")
+	}
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE lrucache_hits_total counter\n",
+		"# TYPE lrucache_size gauge\n",
+		`lrucache_hits_total{cache="users"} 1` + "\n",
+		`lrucache_misses_total{cache="users"} 1` + "\n",
+		`lrucache_evictions_total{cache="users"} 1` + "\n",
+		`lrucache_size{cache="users"} 2` + "\n",
+		`lrucache_capacity{cache="users"} 2` + "\n",
+		`lrucache_capacity{cache="a\"b\\"} 8` + "\n",
+	} {
+		if !strings.Contains(body, line) {
+			t.Error("metrics error", line)
+		}
+	}
+	// Sorted by name
+	if strings.Index(body, `{cache="a`) > strings.Index(body, `{cache="users"}`) {
+		t.Error("metrics order error")
+	}
+
+	var b strings.Builder
+	if n, err := r.WriteTo(&b); err != nil || n != int64(len(body)) || b.String() != body {
+		t.Error("write error")
+	}
+
+	if !r.Unregister("users") || r.Unregister("users") {
+		t.Error("unregister error")
+	}
+	r.Unregister(`a"b\`)
+	b.Reset()
+	if r.WriteTo(&b); b.Len() != 0 {
+		t.Error("empty registry error")
+	}
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	if Register("default", lrucache.New(8)) != nil {
+		t.Error("register error")
+	}
+	defer Unregister("default")
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), `lrucache_capacity{cache="default"} 8`) {
+		t.Error("handler error")
+	}
+}
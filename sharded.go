@@ -0,0 +1,496 @@
+package lrucache
+
+import (
+	"github.com/ZYunH/sbconv"
+	"sync"
+	"time"
+)
+
+var _ Cache = (*ShardedCache)(nil)
+
+// ShardedCache spreads keys over several independent LRU caches by the hash
+// of converted key, each shard has its own lock, which reduces the lock
+// contention on many cores. Use NewSharded to create one.
+//
+// The LRU order is kept in each shard instead of the whole cache.
+type ShardedCache struct {
+	shards   []*LRUCache
+	encoding KeyEncoding
+}
+
+// The buffers used to convert keys before we know which shard to use.
+var bufPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 128)
+		return &b
+	},
+}
+
+// NewSharded creates a new sharded cache with the number of shards and the
+// total max size, which is divided among the shards, see shareOf.
+func NewSharded(shards, maxSize int, opts ...Option) *ShardedCache {
+	if shards <= 0 {
+		panic("shards must be greater than 0")
+	}
+	if maxSize < shards {
+		panic("maxSize must be greater than or equal to shards")
+	}
+	o := newOptions(opts)
+	c := &ShardedCache{shards: make([]*LRUCache, shards), encoding: o.keyEncoding}
+	for i := range c.shards {
+		c.shards[i] = New(int(shareOf(int64(maxSize), shards, i)), shardOptions(opts, o.maxBytes, shards, i)...)
+	}
+	return c
+}
+
+// NewShardedWeighted creates a new sharded cache with the number of shards
+// and the total max cost, which is divided among the shards, see shareOf and
+// NewWeighted.
+func NewShardedWeighted(shards int, maxCost int64, opts ...Option) *ShardedCache {
+	if shards <= 0 {
+		panic("shards must be greater than 0")
+	}
+	if maxCost < int64(shards) {
+		panic("maxCost must be greater than or equal to shards")
+	}
+	o := newOptions(opts)
+	c := &ShardedCache{shards: make([]*LRUCache, shards), encoding: o.keyEncoding}
+	for i := range c.shards {
+		c.shards[i] = NewWeighted(shareOf(maxCost, shards, i), shardOptions(opts, o.maxBytes, shards, i)...)
+	}
+	return c
+}
+
+// Returns the share of shard i when n is divided among the shards, the first
+// n%shards shards have one more than the others, so the total is exactly n.
+func shareOf(n int64, shards, i int) int64 {
+	share := n / int64(shards)
+	if int64(i) < n%int64(shards) {
+		share++
+	}
+	return share
+}
+
+// Returns the options of shard i, the max bytes is divided among the shards,
+// but every shard has at least one byte since zero means unlimited.
+func shardOptions(opts []Option, maxBytes int64, shards, i int) []Option {
+	if maxBytes > 0 {
+		share := shareOf(maxBytes, shards, i)
+		if share < 1 {
+			share = 1
+		}
+		opts = append(opts[:len(opts):len(opts)], WithMaxBytes(share))
+	}
+	return opts
+}
+
+// Returns the shard of a converted key, FNV-1a is used as the hash function.
+func (c *ShardedCache) shard(k string) *LRUCache {
+	h := uint32(2166136261)
+	for i := 0; i < len(k); i++ {
+		h ^= uint32(k[i])
+		h *= 16777619
+	}
+	return c.shards[h%uint32(len(c.shards))]
+}
+
+// Convert key arguments to a pseudo-string in a buffer of bufPool, the buffer
+// must be put back after using the string.
+func (c *ShardedCache) key(args ...interface{}) (string, *[]byte) {
+	k, b, err := c.tryKey(args...)
+	if err != nil {
+		panic(err)
+	}
+	return k, b
+}
+
+// Convert key arguments to a pseudo-string in a buffer of bufPool, the buffer
+// must be put back after using the string if no error is returned.
+func (c *ShardedCache) tryKey(args ...interface{}) (string, *[]byte, error) {
+	b := bufPool.Get().(*[]byte)
+	var err error
+	if c.encoding == CanonicalEncoding {
+		*b, err = appendCanonicalKey((*b)[:0], args...)
+	} else {
+		*b, err = appendKey((*b)[:0], args...)
+	}
+	if err != nil {
+		bufPool.Put(b)
+		return "", nil, err
+	}
+	return sbconv.BytesToString(*b), b, nil
+}
+
+// Set single key and value, see LRUCache.Set.
+func (c *ShardedCache) Set(key, value interface{}) (isRemove bool) {
+	k, b := c.key(key)
+	s := c.shard(k)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	isRemove = s.set(k, value, 0, s.weigh(k, value))
+	bufPool.Put(b)
+	return
+}
+
+// Set single key and value with a time-to-live, see LRUCache.SetWithTTL.
+func (c *ShardedCache) SetWithTTL(key, value interface{}, ttl time.Duration) (isRemove bool) {
+	k, b := c.key(key)
+	s := c.shard(k)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	isRemove = s.set(k, value, expireAt(ttl), s.weigh(k, value))
+	bufPool.Put(b)
+	return
+}
+
+// Set single key and value with a cost, see LRUCache.SetWithCost.
+func (c *ShardedCache) SetWithCost(key, value interface{}, cost int64) (isRemove bool) {
+	if cost < 0 {
+		panic(ErrInvalidCost)
+	}
+	k, b := c.key(key)
+	s := c.shard(k)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	isRemove = s.set(k, value, 0, cost)
+	bufPool.Put(b)
+	return
+}
+
+// Get value via a single key, see LRUCache.Get.
+func (c *ShardedCache) Get(key interface{}) (value interface{}, ok bool) {
+	k, b := c.key(key)
+	s := c.shard(k)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	value, ok = s.get(k)
+	bufPool.Put(b)
+	return
+}
+
+// Set multi-keys and corresponding single value, see LRUCache.MSet.
+func (c *ShardedCache) MSet(kvs ...interface{}) (isRemove bool) {
+	if len(kvs) < 2 {
+		panic(ErrTooFewArgs)
+	}
+	k, b := c.key(kvs[:len(kvs)-1]...)
+	s := c.shard(k)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	isRemove = s.set(k, kvs[len(kvs)-1], 0, s.weigh(k, kvs[len(kvs)-1]))
+	bufPool.Put(b)
+	return
+}
+
+// Set multi-keys and corresponding single value with a time-to-live,
+// see LRUCache.MSetWithTTL.
+func (c *ShardedCache) MSetWithTTL(ttl time.Duration, kvs ...interface{}) (isRemove bool) {
+	if len(kvs) < 2 {
+		panic(ErrTooFewArgs)
+	}
+	k, b := c.key(kvs[:len(kvs)-1]...)
+	s := c.shard(k)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	isRemove = s.set(k, kvs[len(kvs)-1], expireAt(ttl), s.weigh(k, kvs[len(kvs)-1]))
+	bufPool.Put(b)
+	return
+}
+
+// Set multi-keys and corresponding single value with a cost, see
+// LRUCache.MSetWithCost.
+func (c *ShardedCache) MSetWithCost(cost int64, kvs ...interface{}) (isRemove bool) {
+	if len(kvs) < 2 {
+		panic(ErrTooFewArgs)
+	}
+	if cost < 0 {
+		panic(ErrInvalidCost)
+	}
+	k, b := c.key(kvs[:len(kvs)-1]...)
+	s := c.shard(k)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	isRemove = s.set(k, kvs[len(kvs)-1], 0, cost)
+	bufPool.Put(b)
+	return
+}
+
+// Get value via multi-keys, see LRUCache.MGet.
+func (c *ShardedCache) MGet(keys ...interface{}) (value interface{}, ok bool) {
+	k, b := c.key(keys...)
+	s := c.shard(k)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	value, ok = s.get(k)
+	bufPool.Put(b)
+	return
+}
+
+// TrySet is like Set, but it returns an error instead of panic, see
+// LRUCache.TrySet.
+func (c *ShardedCache) TrySet(key, value interface{}) (isRemove bool, err error) {
+	k, b, err := c.tryKey(key)
+	if err != nil {
+		return false, err
+	}
+	s := c.shard(k)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	isRemove = s.set(k, value, 0, s.weigh(k, value))
+	bufPool.Put(b)
+	return
+}
+
+// TryGet is like Get, but it returns an error instead of panic, see
+// LRUCache.TryGet.
+func (c *ShardedCache) TryGet(key interface{}) (value interface{}, ok bool, err error) {
+	k, b, err := c.tryKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+	s := c.shard(k)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	value, ok = s.get(k)
+	bufPool.Put(b)
+	return
+}
+
+// TryMSet is like MSet, but it returns an error instead of panic, see
+// LRUCache.TryMSet.
+func (c *ShardedCache) TryMSet(kvs ...interface{}) (isRemove bool, err error) {
+	if len(kvs) < 2 {
+		return false, ErrTooFewArgs
+	}
+	k, b, err := c.tryKey(kvs[:len(kvs)-1]...)
+	if err != nil {
+		return false, err
+	}
+	s := c.shard(k)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	isRemove = s.set(k, kvs[len(kvs)-1], 0, s.weigh(k, kvs[len(kvs)-1]))
+	bufPool.Put(b)
+	return
+}
+
+// TryMGet is like MGet, but it returns an error instead of panic, see
+// LRUCache.TryMGet.
+func (c *ShardedCache) TryMGet(keys ...interface{}) (value interface{}, ok bool, err error) {
+	k, b, err := c.tryKey(keys...)
+	if err != nil {
+		return nil, false, err
+	}
+	s := c.shard(k)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	value, ok = s.get(k)
+	bufPool.Put(b)
+	return
+}
+
+// Peek value via a single key, see LRUCache.Peek.
+func (c *ShardedCache) Peek(key interface{}) (value interface{}, ok bool) {
+	k, b := c.key(key)
+	s := c.shard(k)
+	s.lock.Lock()
+	if n := s.peek(k); n != nil {
+		value, ok = n.value, true
+	}
+	s.lock.Unlock()
+	bufPool.Put(b)
+	return
+}
+
+// Peek value via multi-keys, see LRUCache.MPeek.
+func (c *ShardedCache) MPeek(keys ...interface{}) (value interface{}, ok bool) {
+	k, b := c.key(keys...)
+	s := c.shard(k)
+	s.lock.Lock()
+	if n := s.peek(k); n != nil {
+		value, ok = n.value, true
+	}
+	s.lock.Unlock()
+	bufPool.Put(b)
+	return
+}
+
+// Contains reports whether the key is in cache, see LRUCache.Contains.
+func (c *ShardedCache) Contains(key interface{}) bool {
+	k, b := c.key(key)
+	s := c.shard(k)
+	s.lock.Lock()
+	ok := s.peek(k) != nil
+	s.lock.Unlock()
+	bufPool.Put(b)
+	return ok
+}
+
+// MContains reports whether the multi-keys are in cache, see
+// LRUCache.MContains.
+func (c *ShardedCache) MContains(keys ...interface{}) bool {
+	k, b := c.key(keys...)
+	s := c.shard(k)
+	s.lock.Lock()
+	ok := s.peek(k) != nil
+	s.lock.Unlock()
+	bufPool.Put(b)
+	return ok
+}
+
+// Get value via a single key or load it, see LRUCache.GetOrLoad.
+func (c *ShardedCache) GetOrLoad(key interface{}, loader func() (interface{}, error)) (value interface{}, err error) {
+	k, b, err := c.tryKey(key)
+	if err != nil {
+		return nil, err
+	}
+	s := c.shard(k)
+	s.lock.Lock()
+	value, err = s.getOrLoad(k, loader)
+	bufPool.Put(b)
+	return
+}
+
+// Get value via multi-keys or load it, see LRUCache.MGetOrLoad.
+func (c *ShardedCache) MGetOrLoad(loader func() (interface{}, error), keys ...interface{}) (value interface{}, err error) {
+	if len(keys) == 0 {
+		return nil, ErrTooFewArgs
+	}
+	k, b, err := c.tryKey(keys...)
+	if err != nil {
+		return nil, err
+	}
+	s := c.shard(k)
+	s.lock.Lock()
+	value, err = s.getOrLoad(k, loader)
+	bufPool.Put(b)
+	return
+}
+
+// Delete value via a single key, see LRUCache.Delete.
+func (c *ShardedCache) Delete(key interface{}) (isDelete bool) {
+	k, b := c.key(key)
+	s := c.shard(k)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	isDelete = s.delete(k)
+	bufPool.Put(b)
+	return
+}
+
+// Delete value via multi-keys, see LRUCache.MDelete.
+func (c *ShardedCache) MDelete(keys ...interface{}) (isDelete bool) {
+	k, b := c.key(keys...)
+	s := c.shard(k)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	isDelete = s.delete(k)
+	bufPool.Put(b)
+	return
+}
+
+// Purge removes all keys from all shards, see LRUCache.Purge.
+func (c *ShardedCache) Purge() {
+	for _, s := range c.shards {
+		s.Purge()
+	}
+}
+
+// Resize changes the total max size (or max cost), which is divided among
+// the shards, see shareOf and LRUCache.Resize.
+func (c *ShardedCache) Resize(maxSize int) (evicted int) {
+	if maxSize < len(c.shards) {
+		panic("maxSize must be greater than or equal to shards")
+	}
+	for i, s := range c.shards {
+		evicted += s.Resize(int(shareOf(int64(maxSize), len(c.shards), i)))
+	}
+	return
+}
+
+// Len returns the number of keys in all shards.
+func (c *ShardedCache) Len() int {
+	l := 0
+	for _, s := range c.shards {
+		l += s.Len()
+	}
+	return l
+}
+
+// Cost returns the total cost of keys in all shards.
+func (c *ShardedCache) Cost() int64 {
+	var cost int64
+	for _, s := range c.shards {
+		cost += s.Cost()
+	}
+	return cost
+}
+
+// Cap returns the total max size (or max cost) of all shards.
+func (c *ShardedCache) Cap() int {
+	n := 0
+	for _, s := range c.shards {
+		n += s.Cap()
+	}
+	return n
+}
+
+// Bytes returns the estimated memory size of keys and values in all shards.
+func (c *ShardedCache) Bytes() int64 {
+	var bytes int64
+	for _, s := range c.shards {
+		bytes += s.Bytes()
+	}
+	return bytes
+}
+
+// HitRatio returns hits / (hits + misses) of all shards, lookups of
+// expired keys are seen as misses. It returns 0 if there is no lookup.
+func (c *ShardedCache) HitRatio() float64 {
+	hits, misses := c.Info()
+	misses += c.Expired()
+	return hitRatio(hits, misses)
+}
+
+// Info returns the number of hits and misses of all shards.
+func (c *ShardedCache) Info() (hits, misses int64) {
+	for _, s := range c.shards {
+		h, m := s.Info()
+		hits += h
+		misses += m
+	}
+	return
+}
+
+// Expired returns the number of lookups which found an expired key in all
+// shards.
+func (c *ShardedCache) Expired() int64 {
+	var expired int64
+	for _, s := range c.shards {
+		expired += s.Expired()
+	}
+	return expired
+}
+
+// Stats returns the statistics of all shards, see LRUCache.Stats.
+func (c *ShardedCache) Stats() Stats {
+	var st Stats
+	for _, s := range c.shards {
+		st.add(s.Stats())
+	}
+	return st
+}
+
+// ResetStats resets the statistics of all shards, see LRUCache.ResetStats.
+func (c *ShardedCache) ResetStats() {
+	for _, s := range c.shards {
+		s.ResetStats()
+	}
+}
+
+// Close stops the janitors of all shards.
+func (c *ShardedCache) Close() {
+	for _, s := range c.shards {
+		s.Close()
+	}
+}
@@ -0,0 +1,237 @@
+package lrucache
+
+import (
+	"testing"
+	"time"
+)
+
+func BenchmarkShardedCache_Get(b *testing.B) {
+	l := NewSharded(16, 1024)
+	for i := 0; i < 1024; i++ {
+		l.Set(i, i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			l.Get(i & 1023)
+			i++
+		}
+	})
+}
+
+func BenchmarkLRUCache_GetParallel(b *testing.B) {
+	l := New(1024)
+	for i := 0; i < 1024; i++ {
+		l.Set(i, i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			l.Get(i & 1023)
+			i++
+		}
+	})
+}
+
+func TestShardedCache(t *testing.T) {
+	l := NewSharded(4, 64)
+	if len(l.shards) != 4 || l.shards[0].maxSize != 16 {
+		t.Error("shards error")
+	}
+
+	for i := 0; i < 32; i++ {
+		if l.Set(i, i) {
+			t.Error("replace while Set error")
+		}
+	}
+	for i := 0; i < 32; i++ {
+		if v, ok := l.Get(i); v != i || !ok {
+			t.Error("Get exists key error")
+		}
+	}
+	if v, ok := l.Get(999); v != nil || ok {
+		t.Error("Get non-existent key error")
+	}
+	if l.Len() != 32 {
+		t.Error("length error")
+	}
+	if hits, misses := l.Info(); hits != 32 || misses != 1 {
+		t.Error("info error")
+	}
+
+	l.MSet(1, "2", "value")
+	if v, ok := l.MGet(1, "2"); v != "value" || !ok {
+		t.Error("MGet error")
+	}
+	if !l.MDelete(1, "2") || l.MDelete(1, "2") || !l.Delete(1) || l.Delete(1) {
+		t.Error("delete error")
+	}
+
+	l.SetWithTTL(1, 1, time.Millisecond)
+	l.MSetWithTTL(time.Millisecond, 1, 2, 3)
+	time.Sleep(time.Millisecond * 5)
+	if _, ok := l.Get(1); ok {
+		t.Error("get expired key error")
+	}
+	if _, ok := l.MGet(1, 2); ok {
+		t.Error("get expired key error")
+	}
+	if l.Expired() != 2 {
+		t.Error("expired info error")
+	}
+	if hits, misses := l.Info(); l.HitRatio() != float64(hits)/float64(hits+misses+2) {
+		t.Error("hit ratio error")
+	}
+
+	loader := func() (interface{}, error) {
+		return "loaded", nil
+	}
+	if v, err := l.GetOrLoad(100, loader); v != "loaded" || err != nil {
+		t.Error("load error")
+	}
+	if v, err := l.MGetOrLoad(loader, 100, 101); v != "loaded" || err != nil {
+		t.Error("load error")
+	}
+	if v, ok := l.MGet(100, 101); v != "loaded" || !ok {
+		t.Error("load error")
+	}
+
+	l.Close()
+}
+
+func TestShardedCache_Eliminate(t *testing.T) {
+	l := NewSharded(3, 8) // 3, 3 and 2 keys in shards
+	for i := 0; i < 100; i++ {
+		l.Set(i, i)
+	}
+	if l.Len() != 8 {
+		t.Error("eliminate error")
+	}
+}
+
+func TestShardedCache_DataRaces(t *testing.T) {
+	l := NewSharded(8, 64, WithJanitor(time.Millisecond))
+	defer l.Close()
+	for i := 0; i < 50; i++ {
+		if i%2 == 0 {
+			go func() {
+				for j := 0; j < 100; j++ {
+					l.SetWithTTL(j, j, time.Millisecond)
+					l.Delete(j - 1)
+				}
+			}()
+		} else {
+			go func() {
+				for j := 0; j < 100; j++ {
+					l.Get(j)
+					l.Len()
+					l.HitRatio()
+					l.Info()
+				}
+			}()
+		}
+	}
+	time.Sleep(time.Millisecond * 100)
+}
+
+func TestShardedCache_Peek_Contains(t *testing.T) {
+	l := NewSharded(4, 64)
+	l.Set(1, 1)
+	l.MSet(2, "2", 2)
+	if v, ok := l.Peek(1); v != 1 || !ok {
+		t.Error("Peek error")
+	}
+	if v, ok := l.MPeek(2, "2"); v != 2 || !ok {
+		t.Error("MPeek error")
+	}
+	if !l.Contains(1) || !l.MContains(2, "2") || l.Contains(2) || l.MContains(1, 2) {
+		t.Error("Contains error")
+	}
+	if hits, misses := l.Info(); hits != 0 || misses != 0 {
+		t.Error("Peek updates info error")
+	}
+}
+
+func TestShardedCache_Purge_Resize(t *testing.T) {
+	l := NewSharded(4, 64)
+	for i := 0; i < 64; i++ {
+		l.Set(i, i)
+	}
+	if l.Resize(32) != l.Len() || l.Len() != 32 || l.shards[0].maxSize != 8 {
+		t.Error("resize error")
+	}
+	if l.Resize(30); l.Cap() != 30 || l.shards[1].maxSize != 8 || l.shards[2].maxSize != 7 {
+		t.Error("resize remainder error", l.Cap())
+	}
+
+	// The remainder is given to the first shards, the total is exact
+	l = NewSharded(16, 17)
+	for i := 0; i < 64; i++ {
+		l.Set(i, i)
+	}
+	if l.Cap() != 17 || l.Len() > 17 || l.shards[0].maxSize != 2 || l.shards[1].maxSize != 1 {
+		t.Error("max size remainder error", l.Cap(), l.Len())
+	}
+	l.Purge()
+	if l.Len() != 0 {
+		t.Error("purge error")
+	}
+}
+
+func TestShardedCache_Try(t *testing.T) {
+	l := NewSharded(4, 64)
+	if isRemove, err := l.TrySet(1, 1); isRemove || err != nil {
+		t.Error("TrySet error")
+	}
+	if v, ok, err := l.TryGet(1); v != 1 || !ok || err != nil {
+		t.Error("TryGet error")
+	}
+	if isRemove, err := l.TryMSet(1, 2, 3); isRemove || err != nil {
+		t.Error("TryMSet error")
+	}
+	if v, ok, err := l.TryMGet(1, 2); v != 3 || !ok || err != nil {
+		t.Error("TryMGet error")
+	}
+
+	if _, err := l.TrySet([]int{}, 1); err != ErrUnsupportedKey {
+		t.Error("TrySet unsupported key error")
+	}
+	if _, _, err := l.TryGet([]int{}); err != ErrUnsupportedKey {
+		t.Error("TryGet unsupported key error")
+	}
+	if _, err := l.TryMSet(1); err != ErrTooFewArgs {
+		t.Error("TryMSet too few args error")
+	}
+	if _, err := l.TryMSet([]int{}, 1); err != ErrUnsupportedKey {
+		t.Error("TryMSet unsupported key error")
+	}
+	if _, _, err := l.TryMGet(1, []int{}); err != ErrUnsupportedKey {
+		t.Error("TryMGet unsupported key error")
+	}
+}
+
+func TestShardedCache_Weighted(t *testing.T) {
+	l := NewShardedWeighted(4, 64)
+	for i := 0; i < 64; i++ {
+		l.SetWithCost(i, i, 4)
+	}
+	if l.Cost() > 64 || l.Cost() != int64(l.Len())*4 {
+		t.Error("cost error", l.Cost())
+	}
+	l.MSetWithCost(16, 1, 2, 3)
+	if v, ok := l.MGet(1, 2); !ok || v != 3 {
+		t.Error("MSetWithCost error")
+	}
+	if l.Resize(32) == 0 || l.Cost() > 32 || l.shards[0].maxCost != 8 {
+		t.Error("resize error")
+	}
+	if l = NewShardedWeighted(4, 66); l.Cap() != 66 || l.shards[1].maxCost != 17 || l.shards[2].maxCost != 16 {
+		t.Error("max cost remainder error", l.Cap())
+	}
+	l.Purge()
+	if l.Cost() != 0 {
+		t.Error("purge error")
+	}
+}
@@ -0,0 +1,39 @@
+package lrucache
+
+import "unsafe"
+
+// Sizer is implemented by values which know their memory size, Size returns
+// the number of bytes used by the value.
+//
+// The memory size of a key and its value is estimated as the length of
+// converted key, plus the overhead of the node and the map entry, plus the
+// size of value. The size of []byte and string is its length, the size of
+// Sizer is returned by Size, and other values are seen as 0.
+//
+// Size is called while holding the lock of cache, so it must not use the
+// cache, otherwise a deadlock will occur.
+type Sizer interface {
+	Size() int64
+}
+
+// The overhead of a key, which is the node, and the string header and the
+// node pointer in map.
+const entryOverhead = int64(unsafe.Sizeof(Entry{}) + unsafe.Sizeof("") + unsafe.Sizeof(&Entry{}))
+
+// Returns the estimated memory size of a converted key and its value.
+func entrySize(k string, value interface{}) int64 {
+	return int64(len(k)) + entryOverhead + valueSize(value)
+}
+
+// Returns the estimated memory size of value, see Sizer.
+func valueSize(value interface{}) int64 {
+	switch v := value.(type) {
+	case []byte:
+		return int64(len(v))
+	case string:
+		return int64(len(v))
+	case Sizer:
+		return v.Size()
+	}
+	return 0
+}
@@ -0,0 +1,84 @@
+package lrucache
+
+import "testing"
+
+type sizedValue int64
+
+func (v sizedValue) Size() int64 { return int64(v) }
+
+func TestValueSize(t *testing.T) {
+	if valueSize([]byte("123")) != 3 || valueSize("12345") != 5 || valueSize(sizedValue(100)) != 100 {
+		t.Error("value size error")
+	}
+	if valueSize(1) != 0 || valueSize(nil) != 0 {
+		t.Error("other value size error")
+	}
+}
+
+func TestLRUCache_Bytes(t *testing.T) {
+	l := New(8)
+	k1 := int64(len(interfaceToBytes(1)))
+	l.Set(1, "1234")
+	if l.Bytes() != k1+entryOverhead+4 {
+		t.Error("bytes error")
+	}
+	l.Set(1, sizedValue(100))
+	if l.Bytes() != k1+entryOverhead+100 {
+		t.Error("update bytes error")
+	}
+	l.MSet(1, 2, []byte("12"))
+	l.Delete(1)
+	if l.Bytes() != int64(len(interfaceToBytes(1, 2)))+entryOverhead+2 {
+		t.Error("delete bytes error")
+	}
+	l.Purge()
+	if l.Bytes() != 0 {
+		t.Error("purge bytes error")
+	}
+
+	// Eliminate the oldest keys until the size fits
+	size := k1 + entryOverhead + 100
+	l = New(8, WithMaxBytes(size*3))
+	for i := 0; i < 3; i++ {
+		if l.Set(i, sizedValue(100)) {
+			t.Error("set error")
+		}
+	}
+	if !l.Set(3, sizedValue(100)) || l.Len() != 3 || l.Contains(0) || l.Bytes() != size*3 {
+		t.Error("max bytes error")
+	}
+	// The entry of 4 is as large as two others
+	if !l.Set(4, sizedValue(size+100)) || l.Len() != 2 || !l.Contains(3) || !l.Contains(4) || l.Bytes() != size*3 {
+		t.Error("max bytes error")
+	}
+	if !l.Set(5, sizedValue(size*3)) || l.Len() != 2 || l.Contains(5) || !l.Contains(3) || l.Bytes() != size*3 {
+		t.Error("large value error")
+	}
+
+	// Works along with the max size
+	l = New(2, WithMaxBytes(size*3))
+	l.Set(1, sizedValue(100))
+	l.Set(2, sizedValue(100))
+	if !l.Set(3, sizedValue(100)) || l.Len() != 2 || l.Bytes() != size*2 {
+		t.Error("max size with max bytes error")
+	}
+}
+
+func TestShardedCache_Bytes(t *testing.T) {
+	l := NewSharded(4, 64, WithMaxBytes(4096))
+	if l.shards[0].maxBytes != 1024 {
+		t.Error("max bytes of shards error")
+	}
+	if l := NewSharded(4, 64, WithMaxBytes(4099)); l.shards[2].maxBytes != 1025 || l.shards[3].maxBytes != 1024 {
+		t.Error("max bytes remainder error")
+	}
+	if l := NewSharded(4, 64, WithMaxBytes(2)); l.shards[1].maxBytes != 1 || l.shards[2].maxBytes != 1 {
+		t.Error("max bytes less than shards error")
+	}
+	for i := 0; i < 64; i++ {
+		l.Set(i, sizedValue(100))
+	}
+	if l.Bytes() > 4096 || l.Len() == 0 || l.Bytes() != int64(l.Len())*(int64(len(interfaceToBytes(1)))+entryOverhead+100) {
+		t.Error("bytes error", l.Bytes())
+	}
+}
@@ -0,0 +1,146 @@
+package lrucache
+
+// SLRU returns a function which creates Segmented LRU policies, it can be
+// passed to WithPolicy directly. Panic will occur if protectedRatio is not
+// in (0, 1).
+//
+// New keys enter the probation segment, and they are promoted to the
+// protected segment only if they are hit again, so the keys used only once
+// never flush the protected ones. The protected segment holds at most
+// protectedRatio of the keys the cache can hold, which is the max size of
+// cache, or the number of keys which fit if the cache is bounded by cost or
+// bytes. Before such a cache is full for the first time, the number is
+// unknown and the keys in cache are used instead. The least recently used
+// keys of the protected segment are demoted to the probation segment, and
+// the victims are chosen from the probation segment first. See SegmentStats
+// for the statistics of segments.
+func SLRU(protectedRatio float64) func() Policy {
+	if protectedRatio <= 0 || protectedRatio >= 1 {
+		panic("protectedRatio must be in (0, 1)")
+	}
+	return func() Policy {
+		return &slruPolicy{ratio: protectedRatio}
+	}
+}
+
+// SegmentStats is the statistics of a segment of policy.
+type SegmentStats struct {
+	Name string
+	// The number of keys in segment.
+	Len int
+	// The number of lookups which found a key in segment.
+	Hits int64
+}
+
+// Implemented by policies which have segments.
+type segmentedPolicy interface {
+	segmentStats() []SegmentStats
+	resetSegmentStats()
+}
+
+type slruPolicy struct {
+	probation, protected entryList
+	ratio                float64
+	// The number of keys the cache can hold, 0 if it is unknown yet.
+	capacity int
+
+	probationHits, protectedHits int64
+}
+
+func (p *slruPolicy) OnInsert(e *Entry) {
+	p.probation.pushBack(e)
+}
+
+func (p *slruPolicy) OnAccess(e *Entry) {
+	if e.list == &p.protected {
+		p.protectedHits++
+		p.protected.remove(e)
+		p.protected.pushBack(e)
+		return
+	}
+
+	// Promote the key, and demote the oldest protected keys if the
+	// protected segment is too large.
+	p.probationHits++
+	p.probation.remove(e)
+	p.protected.pushBack(e)
+	p.demote()
+}
+
+func (p *slruPolicy) setCapacity(n int) {
+	p.capacity = n
+	p.demote()
+}
+
+// Demote the oldest protected keys until the protected segment fits.
+func (p *slruPolicy) demote() {
+	n := p.capacity
+	if n == 0 {
+		n = p.probation.len + p.protected.len
+	}
+	maxProtected := int(p.ratio * float64(n))
+	if maxProtected < 1 {
+		maxProtected = 1
+	}
+	for p.protected.len > maxProtected {
+		d := p.protected.head
+		p.protected.remove(d)
+		p.probation.pushBack(d)
+	}
+}
+
+func (p *slruPolicy) OnRemove(e *Entry) {
+	e.list.remove(e)
+}
+
+func (p *slruPolicy) Victim() *Entry {
+	if p.probation.head != nil {
+		return p.probation.head
+	}
+	return p.protected.head
+}
+
+func (p *slruPolicy) segmentStats() []SegmentStats {
+	return []SegmentStats{
+		{Name: "probation", Len: p.probation.len, Hits: p.probationHits},
+		{Name: "protected", Len: p.protected.len, Hits: p.protectedHits},
+	}
+}
+
+func (p *slruPolicy) resetSegmentStats() {
+	p.probationHits = 0
+	p.protectedHits = 0
+}
+
+// SegmentStats returns the statistics of the segments of policy, e.g. the
+// probation and protected segments of SLRU. It returns nil if the policy
+// has no segment.
+//
+// The hits of segments are reset by ResetStats and Purge, since Purge
+// creates a new policy.
+func (c *LRUCache) SegmentStats() []SegmentStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if p, ok := c.policy.(segmentedPolicy); ok {
+		return p.segmentStats()
+	}
+	return nil
+}
+
+// SegmentStats returns the statistics of the segments of all shards, see
+// LRUCache.SegmentStats.
+func (c *ShardedCache) SegmentStats() []SegmentStats {
+	var stats []SegmentStats
+	for _, s := range c.shards {
+		ss := s.SegmentStats()
+		if stats == nil {
+			stats = ss
+			continue
+		}
+		for i := range ss {
+			stats[i].Len += ss[i].Len
+			stats[i].Hits += ss[i].Hits
+		}
+	}
+	return stats
+}
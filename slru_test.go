@@ -0,0 +1,131 @@
+package lrucache
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Check the segments hold all keys of cache.
+func checkSLRU(t *testing.T, l *LRUCache) {
+	t.Helper()
+	p := l.policy.(*slruPolicy)
+	n := 0
+	for _, list := range []*entryList{&p.probation, &p.protected} {
+		for e := list.head; e != nil; e = e.lnext {
+			if e.list != list || l.m[e.key] != e {
+				t.Fatal("segments error")
+			}
+			n++
+		}
+	}
+	if n != len(l.m) || p.probation.len+p.protected.len != n {
+		t.Fatal("segments length error", n, len(l.m))
+	}
+	if c := p.capacity; c > 0 {
+		n = c
+	}
+	if p.protected.len > 1 && p.protected.len > int(p.ratio*float64(n)) {
+		t.Fatal("protected length error")
+	}
+}
+
+func TestSLRU(t *testing.T) {
+	l := New(4, WithPolicy(SLRU(0.5)))
+	for i := 0; i < 4; i++ {
+		l.Set(i, i)
+	}
+	l.Get(0)
+	l.Get(1)
+	checkSLRU(t, l)
+	if s := l.SegmentStats(); s[0] != (SegmentStats{Name: "probation", Len: 2, Hits: 2}) || s[1].Len != 2 || s[1].Hits != 0 {
+		t.Error("segment stats error", s)
+	}
+
+	// One-hit keys don't flush the protected keys
+	for i := 10; i < 20; i++ {
+		l.Set(i, i)
+		checkSLRU(t, l)
+	}
+	if !l.Contains(0) || !l.Contains(1) || !l.Contains(19) {
+		t.Error("SLRU victim error")
+	}
+
+	// Promote a key, the oldest protected key is demoted
+	l.Get(0)
+	l.Get(19)
+	checkSLRU(t, l)
+	p := l.policy.(*slruPolicy)
+	if p.protected.head.value != 0 || p.probation.tail.value != 1 {
+		t.Error("SLRU demote error")
+	}
+	if s := l.SegmentStats(); s[0].Hits != 3 || s[1].Hits != 1 {
+		t.Error("segment stats error", s)
+	}
+	l.Set(20, 20)
+	if l.Contains(18) || !l.Contains(1) {
+		t.Error("SLRU victim error")
+	}
+
+	l.ResetStats()
+	if s := l.SegmentStats(); s[0].Hits != 0 || s[1].Hits != 0 || s[0].Len+s[1].Len != 4 {
+		t.Error("reset segment stats error", s)
+	}
+	// The protected segment is sized by the capacity of cache, not by the
+	// keys in cache
+	l = New(4, WithPolicy(SLRU(0.5)))
+	l.Set(0, 0)
+	l.Set(1, 1)
+	l.Get(0)
+	l.Get(1)
+	if s := l.SegmentStats(); s[1].Len != 2 {
+		t.Error("SLRU capacity error", s)
+	}
+	l.Resize(2)
+	if s := l.SegmentStats(); s[1].Len != 1 {
+		t.Error("SLRU resize error", s)
+	}
+	checkSLRU(t, l)
+	if New(4).SegmentStats() != nil {
+		t.Error("segment stats of LRU error")
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("SLRU ratio error")
+			}
+		}()
+		SLRU(1)
+	}()
+}
+
+func TestSLRU_Sharded(t *testing.T) {
+	l := NewSharded(4, 64, WithPolicy(SLRU(0.8)))
+	for i := 0; i < 32; i++ {
+		l.Set(i, i)
+		l.Get(i)
+	}
+	s := l.SegmentStats()
+	if len(s) != 2 || s[0].Len+s[1].Len != 32 || s[0].Hits != 32 || s[1].Len == 0 {
+		t.Error("sharded segment stats error", s)
+	}
+}
+
+func TestSLRU_Random(t *testing.T) {
+	l := New(64, WithPolicy(SLRU(0.8)))
+	for i := 0; i < 10000; i++ {
+		k := rand.Intn(128)
+		switch rand.Intn(4) {
+		case 0:
+			l.Delete(k)
+		case 1:
+			l.Set(k, k)
+		default:
+			l.Get(k)
+		}
+		if i%100 == 0 {
+			checkSLRU(t, l)
+		}
+	}
+	checkSLRU(t, l)
+}
@@ -0,0 +1,119 @@
+package lrucache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of the statistics of cache.
+type Stats struct {
+	// The number of lookups which found a key or not, lookups of expired
+	// keys are counted in Expired instead of Misses.
+	Hits    int64
+	Misses  int64
+	Expired int64
+	// The number of new keys and the number of values replaced by set.
+	Sets    int64
+	Updates int64
+	// The number of keys eliminated since the cache is full, the keys
+	// deleted explicitly (including Purge) and the expired keys removed.
+	Evictions   int64
+	Deletes     int64
+	Expirations int64
+	// The number of loaders of GetOrLoad and MGetOrLoad which succeeded or
+	// failed, and the total time spent in them.
+	LoadSuccesses int64
+	LoadFailures  int64
+	TotalLoadTime time.Duration
+	// The current number, total cost and estimated memory size of keys.
+	Len   int
+	Cost  int64
+	Bytes int64
+}
+
+// HitRatio returns hits / (hits + misses), lookups of expired keys are
+// seen as misses. It returns 0 if there is no lookup.
+func (s Stats) HitRatio() float64 {
+	return hitRatio(s.Hits, s.Misses+s.Expired)
+}
+
+func (s *Stats) add(o Stats) {
+	s.Hits += o.Hits
+	s.Misses += o.Misses
+	s.Expired += o.Expired
+	s.Sets += o.Sets
+	s.Updates += o.Updates
+	s.Evictions += o.Evictions
+	s.Deletes += o.Deletes
+	s.Expirations += o.Expirations
+	s.LoadSuccesses += o.LoadSuccesses
+	s.LoadFailures += o.LoadFailures
+	s.TotalLoadTime += o.TotalLoadTime
+	s.Len += o.Len
+	s.Cost += o.Cost
+	s.Bytes += o.Bytes
+}
+
+// The counters of cache, they are updated atomically since they are read
+// without lock.
+type counters struct {
+	hits          int64
+	misses        int64
+	expired       int64
+	sets          int64
+	updates       int64
+	evictions     int64
+	deletes       int64
+	expirations   int64
+	loadSuccesses int64
+	loadFailures  int64
+	// The total time of loaders in nanoseconds.
+	loadTime int64
+}
+
+// Stats returns a snapshot of the statistics of cache.
+func (c *LRUCache) Stats() Stats {
+	c.lock.Lock()
+	s := Stats{Len: len(c.m), Cost: c.cost, Bytes: c.bytes}
+	c.lock.Unlock()
+	s.Hits = atomic.LoadInt64(&c.hits)
+	s.Misses = atomic.LoadInt64(&c.misses)
+	s.Expired = atomic.LoadInt64(&c.expired)
+	s.Sets = atomic.LoadInt64(&c.sets)
+	s.Updates = atomic.LoadInt64(&c.updates)
+	s.Evictions = atomic.LoadInt64(&c.evictions)
+	s.Deletes = atomic.LoadInt64(&c.deletes)
+	s.Expirations = atomic.LoadInt64(&c.expirations)
+	s.LoadSuccesses = atomic.LoadInt64(&c.loadSuccesses)
+	s.LoadFailures = atomic.LoadInt64(&c.loadFailures)
+	s.TotalLoadTime = time.Duration(atomic.LoadInt64(&c.loadTime))
+	return s
+}
+
+// ResetStats resets all counters of cache to zero, the keys are kept.
+func (c *LRUCache) ResetStats() {
+	c.lock.Lock()
+	if p, ok := c.policy.(segmentedPolicy); ok {
+		p.resetSegmentStats()
+	}
+	c.lock.Unlock()
+	atomic.StoreInt64(&c.hits, 0)
+	atomic.StoreInt64(&c.misses, 0)
+	atomic.StoreInt64(&c.expired, 0)
+	atomic.StoreInt64(&c.sets, 0)
+	atomic.StoreInt64(&c.updates, 0)
+	atomic.StoreInt64(&c.evictions, 0)
+	atomic.StoreInt64(&c.deletes, 0)
+	atomic.StoreInt64(&c.expirations, 0)
+	atomic.StoreInt64(&c.loadSuccesses, 0)
+	atomic.StoreInt64(&c.loadFailures, 0)
+	atomic.StoreInt64(&c.loadTime, 0)
+}
+
+// Returns hits / (hits + misses), 0 if there is no lookup.
+func hitRatio(hits, misses int64) float64 {
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
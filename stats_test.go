@@ -0,0 +1,78 @@
+package lrucache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLRUCache_Stats(t *testing.T) {
+	l := New(2)
+	if l.HitRatio() != 0 || l.Stats().HitRatio() != 0 {
+		t.Error("empty hit ratio error")
+	}
+
+	l.Set(1, 1)
+	l.Set(1, 2)
+	l.Set(2, 2)
+	l.Set(3, 3)
+	l.Get(2)
+	l.Get(1)
+	l.SetWithTTL(4, 4, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	l.Get(4)
+	l.Set(5, 5)
+	l.Delete(5)
+	l.GetOrLoad(6, func() (interface{}, error) {
+		time.Sleep(time.Millisecond)
+		return 6, nil
+	})
+	l.GetOrLoad(7, func() (interface{}, error) {
+		return nil, errors.New("load error")
+	})
+
+	s := l.Stats()
+	if s.Hits != 1 || s.Misses != 3 || s.Expired != 1 {
+		t.Error("lookups stats error", s)
+	}
+	if s.Sets != 6 || s.Updates != 1 || s.Evictions != 2 || s.Deletes != 1 || s.Expirations != 1 {
+		t.Error("sets stats error", s)
+	}
+	if s.LoadSuccesses != 1 || s.LoadFailures != 1 || s.TotalLoadTime < time.Millisecond {
+		t.Error("load stats error", s)
+	}
+	if s.Len != 2 || s.Cost != 2 || s.Bytes != l.Bytes() || s.HitRatio() != 0.2 || l.HitRatio() != 0.2 {
+		t.Error("size stats error", s)
+	}
+
+	l.Set(8, 8)
+	l.Purge()
+	if s = l.Stats(); s.Deletes != 3 || s.Len != 0 {
+		t.Error("purge stats error", s)
+	}
+
+	l.ResetStats()
+	l.Set(9, 9)
+	if s = l.Stats(); s != (Stats{Sets: 1, Len: 1, Cost: 1, Bytes: l.Bytes()}) {
+		t.Error("reset stats error", s)
+	}
+}
+
+func TestShardedCache_Stats(t *testing.T) {
+	l := NewSharded(4, 64)
+	if l.HitRatio() != 0 {
+		t.Error("empty hit ratio error")
+	}
+	for i := 0; i < 8; i++ {
+		l.Set(i, i)
+		l.Get(i)
+		l.Get(i + 8)
+	}
+	if s := l.Stats(); s.Sets != 8 || s.Hits != 8 || s.Misses != 8 || s.Len != 8 || s.HitRatio() != 0.5 {
+		t.Error("stats error", s)
+	}
+	l.ResetStats()
+	if s := l.Stats(); s.Sets != 0 || s.Hits != 0 || s.Len != 8 {
+		t.Error("reset stats error", s)
+	}
+}
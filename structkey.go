@@ -0,0 +1,209 @@
+package lrucache
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// The plan to convert a struct or array key, it has all the fields of the
+// key, the nested structs and arrays are flattened in order.
+type keyPlan struct {
+	fields []fieldPlan
+	// Indicates whether all fields are of built-in kinds, then the converted
+	// key can be decoded to a value of its type.
+	decodable bool
+}
+
+type fieldPlan struct {
+	offset uintptr
+	typ    reflect.Type
+	// The type word of interface{} holding the field. For built-in kinds, it
+	// is the built-in type of the same kind, e.g. string for a named string
+	// type, since they have the same memory layout.
+	efaceType unsafe.Pointer
+}
+
+// The built-in key types of kinds.
+var builtinKeyTypes = map[reflect.Kind]reflect.Type{
+	reflect.Bool:       reflect.TypeOf(false),
+	reflect.Uint8:      reflect.TypeOf(uint8(0)),
+	reflect.Int8:       reflect.TypeOf(int8(0)),
+	reflect.Uint16:     reflect.TypeOf(uint16(0)),
+	reflect.Int16:      reflect.TypeOf(int16(0)),
+	reflect.Uint32:     reflect.TypeOf(uint32(0)),
+	reflect.Int32:      reflect.TypeOf(int32(0)),
+	reflect.Float32:    reflect.TypeOf(float32(0)),
+	reflect.Uint64:     reflect.TypeOf(uint64(0)),
+	reflect.Int64:      reflect.TypeOf(int64(0)),
+	reflect.Float64:    reflect.TypeOf(float64(0)),
+	reflect.Complex64:  reflect.TypeOf(complex64(0)),
+	reflect.Complex128: reflect.TypeOf(complex128(0)),
+	reflect.Int:        reflect.TypeOf(int(0)),
+	reflect.Uint:       reflect.TypeOf(uint(0)),
+	reflect.String:     reflect.TypeOf(""),
+	reflect.Slice:      reflect.TypeOf([]byte(nil)),
+}
+
+// Returns the plan of a struct or array type, nil if any field is not
+// supported. The plan of a named type of built-in kind has one field, which
+// is the key itself.
+func newKeyPlan(typ reflect.Type) *keyPlan {
+	p := &keyPlan{decodable: true}
+	if !p.add(typ, 0, false) {
+		return nil
+	}
+	return p
+}
+
+func (p *keyPlan) add(typ reflect.Type, offset uintptr, nested bool) bool {
+	if nested {
+		// A nested field of custom key type is converted by its encoder,
+		// it must not be stored in interface{} directly.
+		if t := loadCustomKeyRegistry().types[typ]; t != nil && t.plan == nil || implementsKeyEncoder(typ) {
+			if isDirectIface(typ) {
+				return false
+			}
+			p.fields = append(p.fields, fieldPlan{offset: offset, typ: typ, efaceType: efaceTypeOf(typ)})
+			p.decodable = false
+			return true
+		}
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			if !p.add(f.Type, offset+f.Offset, true) {
+				return false
+			}
+		}
+		return true
+	case reflect.Array:
+		for i := 0; i < typ.Len(); i++ {
+			if !p.add(typ.Elem(), offset+uintptr(i)*typ.Elem().Size(), true) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice:
+		if typ.Elem().Kind() != reflect.Uint8 {
+			return false
+		}
+	}
+
+	builtin := builtinKeyTypes[typ.Kind()]
+	if builtin == nil {
+		return false
+	}
+	p.fields = append(p.fields, fieldPlan{offset: offset, typ: typ, efaceType: efaceTypeOf(builtin)})
+	return true
+}
+
+// Returns the type word of interface{} holding a value of typ.
+func efaceTypeOf(typ reflect.Type) unsafe.Pointer {
+	v := reflect.Zero(typ).Interface()
+	return (*[2]unsafe.Pointer)(unsafe.Pointer(&v))[0]
+}
+
+// Reports whether a value of typ is stored in the data word of interface{}
+// directly instead of a pointer to it.
+func isDirectIface(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return true
+	case reflect.Struct:
+		return typ.NumField() == 1 && isDirectIface(typ.Field(0).Type)
+	case reflect.Array:
+		return typ.Len() == 1 && isDirectIface(typ.Elem())
+	}
+	return false
+}
+
+// Returns the pointer to the memory of key, which is a struct, an array, a
+// named type of built-in kind or a pointer to them, nil if it is a nil
+// pointer.
+func keyPointer(v interface{}) unsafe.Pointer {
+	// The data word is the pointer itself for pointers, and a pointer to the
+	// value for others, since the supported keys are never stored in
+	// interface{} directly.
+	return (*mockEFace)(unsafe.Pointer(&v)).data
+}
+
+// Returns the field of key at ptr as interface{} without allocation.
+func (f *fieldPlan) value(ptr unsafe.Pointer) interface{} {
+	var v interface{}
+	e := (*[2]unsafe.Pointer)(unsafe.Pointer(&v))
+	e[0] = f.efaceType
+	e[1] = unsafe.Add(ptr, f.offset)
+	return v
+}
+
+// Append a struct or array key to b, the layout is tag, type id and all the
+// fields converted by appendKey.
+func appendStructKey(b []byte, t *customKeyType, v interface{}) ([]byte, error) {
+	ptr := keyPointer(v)
+	if ptr == nil {
+		return b, ErrUnsupportedKey
+	}
+	b = append(b, uint8(reflect.Struct), (*(*[4]byte)(unsafe.Pointer(&t.id)))[0], (*(*[4]byte)(unsafe.Pointer(&t.id)))[1],
+		(*(*[4]byte)(unsafe.Pointer(&t.id)))[2], (*(*[4]byte)(unsafe.Pointer(&t.id)))[3])
+	var err error
+	for i := range t.plan.fields {
+		if b, err = appendKey(b, t.plan.fields[i].value(ptr)); err != nil {
+			return b, err
+		}
+	}
+	return b, nil
+}
+
+// Append a struct or array key to b in CanonicalEncoding, the layout is tag,
+// length of type name, type name and all the fields converted by
+// appendCanonicalKey.
+func appendCanonicalStructKey(b []byte, t *customKeyType, v interface{}) ([]byte, error) {
+	ptr := keyPointer(v)
+	if ptr == nil {
+		return b, ErrUnsupportedKey
+	}
+	b = appendUvarint(append(b, uint8(reflect.Struct)), uint64(len(t.name)))
+	b = append(b, t.name...)
+	var err error
+	for i := range t.plan.fields {
+		if b, err = appendCanonicalKey(b, t.plan.fields[i].value(ptr)); err != nil {
+			return b, err
+		}
+	}
+	return b, nil
+}
+
+// Decode a struct or array key from b, which is the bytes following the tag.
+// The returned int is the number of used bytes.
+func decodeStructKey(b []byte) (interface{}, int, error) {
+	if len(b) < 4 {
+		return nil, 0, errShortBytes
+	}
+	var id uint32
+	copy((*[4]byte)(unsafe.Pointer(&id))[:], b)
+	t := loadCustomKeyRegistry().ids[id]
+	if t == nil || t.plan == nil {
+		return nil, 0, errUnknownType
+	}
+	args, n, err := decodeArgs(b[4:], len(t.plan.fields))
+	if err != nil {
+		return nil, 0, err
+	}
+	return t.structKey(args, b[4:4+n]), 4 + n, nil
+}
+
+// Returns the key of type t with the decoded fields, a CustomKey with the
+// converted fields is returned if the key is not decodable.
+func (t *customKeyType) structKey(fields []interface{}, data []byte) interface{} {
+	if !t.plan.decodable {
+		return CustomKey{Type: t.typ, Data: append([]byte{}, data...)}
+	}
+	v := reflect.New(t.typ)
+	for i, f := range t.plan.fields {
+		reflect.NewAt(f.typ, unsafe.Add(v.UnsafePointer(), f.offset)).Elem().
+			Set(reflect.ValueOf(fields[i]).Convert(f.typ))
+	}
+	return v.Elem().Interface()
+}
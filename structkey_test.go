@@ -0,0 +1,231 @@
+package lrucache
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testRegion string
+
+type testTenantKey struct {
+	Tenant string
+	Region testRegion
+	ID     uint64
+}
+
+type testOtherKey struct {
+	Tenant string
+	Region testRegion
+	ID     uint64
+}
+
+type testNestedKey struct {
+	flag  bool
+	inner testTenantKey
+	pos   [2]int16
+	raw   []byte
+}
+
+func BenchmarkInterfaceToBytesWithBufStruct(b *testing.B) {
+	buf := make([]byte, 0, 128)
+	key := testTenantKey{"tenant", "region", 1}
+	for i := 0; i < b.N; i++ {
+		interfaceToBytesWithBuf(buf, key)
+	}
+}
+
+func TestStructKey(t *testing.T) {
+	l := New(64)
+	l.Set(testTenantKey{"a", "b", 1}, 1)
+	if v, ok := l.Get(testTenantKey{"a", "b", 1}); v != 1 || !ok {
+		t.Error("get struct key error")
+	}
+	if v, ok := l.Get(&testTenantKey{"a", "b", 1}); v != 1 || !ok {
+		t.Error("get pointer to struct key error")
+	}
+	if _, ok := l.Get(testTenantKey{"a", "b", 2}); ok {
+		t.Error("get non-existent struct key error")
+	}
+
+	// Keys of different types never conflict
+	if _, ok := l.Get(testOtherKey{"a", "b", 1}); ok {
+		t.Error("struct keys of different types conflict")
+	}
+	if _, ok := l.MGet("a", "b", uint64(1)); ok {
+		t.Error("struct key and multi-keys conflict")
+	}
+
+	// Nested structs and arrays
+	l.Set(testNestedKey{true, testTenantKey{"a", "b", 1}, [2]int16{1, 2}, []byte("c")}, 2)
+	if v, ok := l.Get(testNestedKey{true, testTenantKey{"a", "b", 1}, [2]int16{1, 2}, []byte("c")}); v != 2 || !ok {
+		t.Error("get nested struct key error")
+	}
+	if _, ok := l.Get(testNestedKey{true, testTenantKey{"a", "b", 1}, [2]int16{1, 3}, []byte("c")}); ok {
+		t.Error("get non-existent nested struct key error")
+	}
+	l.Set([3]string{"a", "b", "c"}, 3)
+	if v, ok := l.Get(&[3]string{"a", "b", "c"}); v != 3 || !ok {
+		t.Error("get array key error")
+	}
+	l.MSet(testTenantKey{"a", "b", 1}, 2, 4)
+	if v, ok := l.MGet(testTenantKey{"a", "b", 1}, 2); v != 4 || !ok {
+		t.Error("get multi struct keys error")
+	}
+
+	// Unsupported fields
+	for _, v := range []interface{}{
+		struct{ p *int }{},
+		struct{ m map[int]int }{},
+		struct{ s []int }{},
+		[2]interface{}{},
+		(*testTenantKey)(nil),
+		&[]int{},
+	} {
+		if _, err := l.TrySet(v, 1); err != ErrUnsupportedKey {
+			t.Errorf("unsupported struct key error %T", v)
+		}
+	}
+}
+
+func TestStructKeyDecode(t *testing.T) {
+	for _, encoding := range []KeyEncoding{NativeEncoding, CanonicalEncoding} {
+		l := New(64, WithKeyEncoding(encoding))
+		nested := testNestedKey{true, testTenantKey{"a", "b", 1}, [2]int16{1, 2}, []byte("c")}
+		l.Set(&testTenantKey{"a", "b", 1}, 1)
+		l.MSet(1, nested, [2]string{"a", "b"}, 2)
+		if v, ok := l.MGet(1, &nested, [2]string{"a", "b"}); v != 2 || !ok {
+			t.Error("get struct key error")
+		}
+
+		keys := l.Keys()
+		if len(keys) != 2 || len(keys[0]) != 3 || len(keys[1]) != 1 {
+			t.Fatal("decode struct key error")
+		}
+		if keys[1][0] != (testTenantKey{"a", "b", 1}) {
+			t.Error("decode struct key error", keys[1][0])
+		}
+		if !reflect.DeepEqual(keys[0][1], nested) || keys[0][2] != [2]string{"a", "b"} {
+			t.Error("decode nested struct key error", keys[0][1], keys[0][2])
+		}
+	}
+
+	// Struct with a custom key field can't be decoded
+	type event struct {
+		At   time.Time
+		User testUserID
+		ID   int
+	}
+	now := time.Now()
+	for _, encoding := range []KeyEncoding{NativeEncoding, CanonicalEncoding} {
+		l := New(64, WithKeyEncoding(encoding))
+		l.Set(event{now, testUserID{"a", 1}, 1}, 1)
+		if v, ok := l.Get(event{now, testUserID{"a", 1}, 1}); v != 1 || !ok {
+			t.Error("get struct key with custom field error")
+		}
+		if _, ok := l.Get(event{now, testUserID{"a", 2}, 1}); ok {
+			t.Error("get struct key with custom field error")
+		}
+		if key := l.Keys()[0][0].(CustomKey); key.Type != reflect.TypeOf(event{}) || len(key.Data) == 0 {
+			t.Error("decode struct key with custom field error")
+		}
+	}
+
+	// Invalid bytes
+	b := interfaceToBytes(testTenantKey{"a", "b", 1})
+	for i := 1; i < len(b); i++ {
+		if _, err := bytesToInterfaces(b[:i]); err != errShortBytes {
+			t.Error("decode short struct key error", i, err)
+		}
+	}
+	b[1] = 0xff
+	if _, err := bytesToInterfaces(b); err != errUnknownType {
+		t.Error("decode unknown struct key error")
+	}
+	b, _ = appendCanonicalKey(nil, testTenantKey{"a", "b", 1})
+	b[2] = 'x'
+	if _, err := canonicalBytesToInterfaces(b); err != errUnknownType {
+		t.Error("decode unknown canonical struct key error")
+	}
+}
+
+// Restore the registry of custom key types after the test, then the test
+// can register types and still be run repeatedly.
+func restoreKeyRegistry(t *testing.T) {
+	r := loadCustomKeyRegistry()
+	t.Cleanup(func() {
+		customKeyTypes.Store(r)
+	})
+}
+
+func TestStructKeyRegistered(t *testing.T) {
+	restoreKeyRegistry(t)
+	type point struct{ x, y int }
+	if fmt.Sprint(bytesToInterfaces(interfaceToBytes(point{1, 2}))) != "[{1 2}] <nil>" {
+		t.Error("decode struct key error")
+	}
+	if string(interfaceToBytes(&point{1, 2})) != string(interfaceToBytes(point{1, 2})) {
+		t.Error("pointer to struct key error")
+	}
+	l := New(8)
+	l.Set(point{1, 2}, 1)
+	c := New(8, WithKeyEncoding(CanonicalEncoding))
+	c.Set(point{1, 2}, 1)
+
+	// The registered encoder replaces the plan
+	RegisterKeyEncoder(reflect.TypeOf(point{}), func(b []byte, key interface{}) []byte {
+		return append(b, byte(key.(point).x))
+	})
+	if string(interfaceToBytes(point{1, 2})) != string(interfaceToBytes(point{1, 3})) {
+		t.Error("registered encoder error")
+	}
+	if string(interfaceToBytes(&point{1, 2})) != string(interfaceToBytes(point{1, 3})) {
+		t.Error("pointer to registered type error")
+	}
+	if _, err := appendKey(nil, (*point)(nil)); err != ErrUnsupportedKey {
+		t.Error("nil pointer to registered type error")
+	}
+
+	// The keys converted by the plan are still decodable
+	for _, l := range []*LRUCache{l, c} {
+		if keys := l.Keys(); len(keys) != 1 || keys[0][0] != (point{1, 2}) {
+			t.Error("decode replaced plan error", keys)
+		}
+		if _, ok := l.Get(point{1, 2}); ok {
+			t.Error("get replaced plan error")
+		}
+		l.Set(&point{1, 3}, 2)
+		if v, ok := l.Get(point{1, 4}); v != 2 || !ok {
+			t.Error("get registered type error")
+		}
+	}
+}
+
+type testTenantID int64
+
+func TestNamedBuiltinKey(t *testing.T) {
+	for _, encoding := range []KeyEncoding{NativeEncoding, CanonicalEncoding} {
+		l := New(8, WithKeyEncoding(encoding))
+		l.Set(testTenantID(5), 1)
+		l.Set(int64(5), 2)
+		l.Set(testRegion("eu"), 3)
+		if v, ok := l.Get(testTenantID(5)); v != 1 || !ok {
+			t.Error("named built-in key error")
+		}
+		if v, ok := l.Get(int64(5)); v != 2 || !ok {
+			t.Error("named built-in key conflicts with built-in key")
+		}
+		id := testTenantID(5)
+		if v, ok := l.Get(&id); v != 1 || !ok {
+			t.Error("pointer to named built-in key error")
+		}
+		keys := l.Keys()
+		if len(keys) != 3 || keys[0][0] != testTenantID(5) || keys[1][0] != int64(5) || keys[2][0] != testRegion("eu") {
+			t.Error("decode named built-in key error", keys)
+		}
+	}
+	if _, err := appendKey(nil, (*testTenantID)(nil)); err != ErrUnsupportedKey {
+		t.Error("nil pointer to named built-in key error")
+	}
+}
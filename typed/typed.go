@@ -0,0 +1,189 @@
+// Package typed provides a type-safe LRU cache, which stores typed keys and
+// values directly in the map without converting them via interface{}.
+package typed
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Cache is a concurrent-safe LRU cache with typed keys and values, use New
+// to create one.
+type Cache[K comparable, V any] struct {
+	// The counters are placed first to keep them 64-bit aligned, which is
+	// required by the atomic operations on 32-bit platforms.
+	hits   int64
+	misses int64
+
+	m       map[K]*node[K, V]
+	root    *node[K, V]
+	maxSize int
+
+	lock sync.Mutex
+}
+
+type node[K comparable, V any] struct {
+	key   K
+	value V
+	prev  *node[K, V]
+	next  *node[K, V]
+	// Indicates whether the node holds a key, since the zero value of K
+	// may be a valid key.
+	used bool
+}
+
+// New creates a new LRU cache with max size.
+func New[K comparable, V any](maxSize int) *Cache[K, V] {
+	if maxSize <= 0 {
+		panic("maxSize must be greater than 0")
+	}
+	root := &node[K, V]{}
+	root.next = root
+	root.prev = root
+	return &Cache[K, V]{m: make(map[K]*node[K, V], maxSize), root: root, maxSize: maxSize}
+}
+
+// Set single key and value.
+//
+// The returned value indicates whether a key is eliminated from cache.
+func (c *Cache[K, V]) Set(key K, value V) (isRemove bool) {
+	c.lock.Lock()
+	isRemove = c.set(key, value)
+	c.lock.Unlock()
+	return
+}
+
+func (c *Cache[K, V]) set(key K, value V) bool {
+	n := c.m[key]
+	if n != nil {
+		// Hits a key, we just update its value.
+		n.value = value
+		return false
+	}
+
+	if len(c.m) < c.maxSize-1 {
+		// Cache is not full, insert a new node
+		n = &node[K, V]{key: key, value: value, used: true}
+		n.next = c.root
+		n.prev = c.root.prev
+		c.m[key] = n
+
+		c.root.prev.next = n
+		c.root.prev = n
+		return false
+	}
+
+	// Cache is full, replace the oldest one with the new node,
+	// in this case, we just replace the original root with the
+	// new root, and make the original root.next become the new root.
+	isRemove := c.root.used
+	if isRemove {
+		delete(c.m, c.root.key)
+	}
+	c.root.key = key
+	c.root.value = value
+	c.root.used = true
+	c.m[key] = c.root
+	c.root = c.root.next
+	return isRemove
+}
+
+// Get value via a single key.
+func (c *Cache[K, V]) Get(key K) (value V, ok bool) {
+	c.lock.Lock()
+	n := c.m[key]
+	if n != nil {
+		// Hits a key, drop it from the original location, and insert it
+		// to the location between root.prev and root (The latest location in cache)
+		atomic.AddInt64(&c.hits, 1)
+		n.prev.next = n.next
+		n.next.prev = n.prev
+		if n == c.root {
+			c.root = c.root.next
+		}
+		n.prev = c.root.prev
+		n.next = c.root
+
+		c.root.prev.next = n
+		c.root.prev = n
+
+		value, ok = n.value, true
+	} else {
+		atomic.AddInt64(&c.misses, 1)
+	}
+	c.lock.Unlock()
+	return
+}
+
+// Delete value via a single key.
+//
+// The returned value indicates whether the key is in cache.
+func (c *Cache[K, V]) Delete(key K) (isDelete bool) {
+	c.lock.Lock()
+	n := c.m[key]
+	if n != nil {
+		c.remove(n)
+		isDelete = true
+	}
+	c.lock.Unlock()
+	return
+}
+
+// Remove a node from both the map and the linked list, see
+// lrucache.LRUCache for the details.
+func (c *Cache[K, V]) remove(n *node[K, V]) {
+	var (
+		zeroK K
+		zeroV V
+	)
+	delete(c.m, n.key)
+	n.key = zeroK
+	n.value = zeroV
+	n.used = false
+
+	if len(c.m) == c.maxSize-1 {
+		// The cache was full.
+		if n != c.root {
+			n.prev.next = n.next
+			n.next.prev = n.prev
+
+			n.prev = c.root.prev
+			n.next = c.root
+			c.root.prev.next = n
+			c.root.prev = n
+			c.root = n
+		}
+		return
+	}
+
+	n.prev.next = n.next
+	n.next.prev = n.prev
+}
+
+// Len returns the number of keys in cache.
+func (c *Cache[K, V]) Len() int {
+	c.lock.Lock()
+	l := len(c.m)
+	c.lock.Unlock()
+
+	return l
+}
+
+// HitRatio returns hits / (hits + misses), it returns 0 if there is no
+// lookup.
+func (c *Cache[K, V]) HitRatio() float64 {
+	hits := atomic.LoadInt64(&c.hits)
+	misses := atomic.LoadInt64(&c.misses)
+	if hits+misses == 0 {
+		return 0
+	}
+
+	return float64(hits) / float64(misses+hits)
+}
+
+// Info returns the number of hits and misses.
+func (c *Cache[K, V]) Info() (hits, misses int64) {
+	hits = atomic.LoadInt64(&c.hits)
+	misses = atomic.LoadInt64(&c.misses)
+	return
+}
@@ -0,0 +1,132 @@
+package typed
+
+import (
+	"testing"
+	"time"
+)
+
+type compositeKey struct {
+	tenant string
+	id     int
+}
+
+func BenchmarkCache_Get(b *testing.B) {
+	l := New[int, int](64)
+	l.Set(1, 1)
+	for i := 0; i < b.N; i++ {
+		l.Get(1)
+	}
+}
+
+func TestCache_Set_Get(t *testing.T) {
+	l := New[int, string](3)
+
+	if l.Set(1, "1") {
+		t.Error("replace while Set error")
+	}
+	if v, ok := l.Get(1); v != "1" || !ok {
+		t.Error("Get exists key error")
+	}
+	if v, ok := l.Get(999); v != "" || ok {
+		t.Error("Get non-existent key error")
+	}
+
+	l.Set(2, "2")
+	l.Set(3, "3")
+	if !l.Set(4, "4") {
+		t.Error("eliminate key error")
+	}
+	if _, ok := l.Get(1); ok {
+		t.Error("Get eliminated key error")
+	}
+
+	// Now the key in cache is (4,3,2), left is newer
+	l.Get(2)      // Cache : (2,4,3)
+	l.Set(5, "5") // Cache : (5,2,4)
+	if _, ok := l.Get(3); ok {
+		t.Error("Get sort error")
+	}
+	if l.root.value != "4" || l.root.next.value != "2" || l.root.next.next.value != "5" {
+		t.Error("linked list error")
+	}
+}
+
+func TestCache_ZeroKey(t *testing.T) {
+	l := New[compositeKey, int](2)
+	l.Set(compositeKey{}, 1)
+	l.Set(compositeKey{"a", 1}, 2)
+	l.Delete(compositeKey{"a", 1})
+	if l.Set(compositeKey{"b", 2}, 3) {
+		t.Error("eliminate key after delete error")
+	}
+	if v, ok := l.Get(compositeKey{}); v != 1 || !ok {
+		t.Error("zero key error")
+	}
+}
+
+func TestCache_Delete(t *testing.T) {
+	l := New[string, int](3)
+	if l.Delete("1") {
+		t.Error("delete non-existent key error")
+	}
+
+	l.Set("1", 1)
+	l.Set("2", 2)
+	l.Set("3", 3)
+	if !l.Delete("1") || l.Len() != 2 {
+		t.Error("delete root error")
+	}
+	if l.Set("4", 4) {
+		t.Error("eliminate key after delete error")
+	}
+	if !l.Delete("3") || l.Len() != 2 {
+		t.Error("delete error")
+	}
+	if l.Set("5", 5) || !l.Set("6", 6) {
+		t.Error("eliminate key error")
+	}
+	if _, ok := l.Get("2"); ok {
+		t.Error("Get eliminated key error")
+	}
+}
+
+func TestCache_Others(t *testing.T) {
+	l := New[int, int](64)
+	l.Set(1, 1)
+	l.Get(1)
+	l.Get(2)
+
+	if l.Len() != 1 {
+		t.Error("length error")
+	}
+	if i, j := l.Info(); !(i == j && i == 1) {
+		t.Error("info error")
+	}
+	if l.HitRatio() != float64(0.5) {
+		t.Error("hit ratio error")
+	}
+}
+
+func TestDataRaces(t *testing.T) {
+	l := New[int, int](64)
+	for i := 0; i < 50; i++ {
+		if i%2 == 0 {
+			go func() {
+				for j := 0; j < 100; j++ {
+					l.Set(j, j)
+					l.Delete(j - 1)
+				}
+			}()
+		} else {
+			go func() {
+				for j := 0; j < 100; j++ {
+					l.Get(j)
+					l.Len()
+					l.HitRatio()
+					l.Info()
+				}
+			}()
+		}
+	}
+	time.Sleep(time.Millisecond * 100)
+}